@@ -0,0 +1,366 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ExitOK - the command completed successfully
+const ExitOK = 0
+
+// ExitError - the command failed while running
+const ExitError = 1
+
+// ExitUsage - the command line was invalid (unknown command or flag, missing value, etc)
+const ExitUsage = 2
+
+// FlagKind determines how a flag's value is parsed
+type FlagKind int
+
+const (
+	// StringFlag takes a single value. If repeated, the last value wins
+	StringFlag FlagKind = iota
+	// BoolFlag takes no value (--flag), or an explicit one (--flag=false)
+	BoolFlag
+	// StringSliceFlag may be repeated; every value is kept
+	StringSliceFlag
+)
+
+// Flag describes a single command line flag
+type Flag struct {
+	Name      string // Long name, without the leading dashes
+	Short     string // Optional single letter name
+	Usage     string
+	ValueName string // Placeholder shown in the help text, e.g. "file"
+	Kind      FlagKind
+	Default   string
+	Required  bool
+}
+
+// Command is a node within the command tree. Leaf commands have a Run function.
+type Command struct {
+	Name        string
+	Short       string // One line description, shown in lists
+	Long        string // Full description, shown in the command's help
+	ArgsUsage   string // Positional arguments, e.g. "<component id>"
+	MinArgs     int
+	MaxArgs     int // -1 for unlimited
+	Flags       []*Flag
+	Subcommands []*Command
+	Run         func(ctx *Context) error
+
+	// Before runs ahead of the selected command (or any of its descendants)
+	Before func(ctx *Context) error
+	// Finish replaces the default error reporting for the command and its descendants, and returns the exit code
+	Finish func(ctx *Context, err error) int
+
+	parent *Command
+}
+
+// UsageError is returned when the command line is invalid. It results in the help text being printed and ExitUsage.
+type UsageError struct {
+	Command *Command
+	Message string
+}
+
+func (ue *UsageError) Error() string {
+	return ue.Message
+}
+
+// NewUsageError creates a usage error for the command being run
+func NewUsageError(ctx *Context, format string, args ...interface{}) error {
+	return &UsageError{Command: ctx.Command, Message: fmt.Sprintf(format, args...)}
+}
+
+// ExitCodeError allows a command to fail with a specific exit code
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (ece *ExitCodeError) Error() string {
+	return ece.Err.Error()
+}
+
+// Context carries the parsed command line into a command's Run function
+type Context struct {
+	Command *Command
+	Args    []string
+	Stdout  io.Writer
+	Stderr  io.Writer
+
+	values map[string][]string
+}
+
+// AddCommand attaches subcommands to a command
+func (c *Command) AddCommand(subcommands ...*Command) {
+	for _, curSub := range subcommands {
+		curSub.parent = c
+		c.Subcommands = append(c.Subcommands, curSub)
+	}
+}
+
+// Path returns the full name of the command, e.g. "rezolvr state list"
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+// findSubcommand - locate an immediate subcommand by name
+func (c *Command) findSubcommand(name string) *Command {
+	for _, curSub := range c.Subcommands {
+		if curSub.Name == name {
+			return curSub
+		}
+	}
+	return nil
+}
+
+// allFlags - the command's own flags, followed by those inherited from its ancestors
+func (c *Command) allFlags() []*Flag {
+	flags := make([]*Flag, 0)
+	for cur := c; cur != nil; cur = cur.parent {
+		flags = append(flags, cur.Flags...)
+	}
+	return flags
+}
+
+func (c *Command) lookupFlag(name string, short bool) *Flag {
+	for _, curFlag := range c.allFlags() {
+		if (short && curFlag.Short == name) || (!short && curFlag.Name == name) {
+			return curFlag
+		}
+	}
+	return nil
+}
+
+// String returns the value of a string flag, or its default
+func (ctx *Context) String(name string) string {
+	vals := ctx.values[name]
+	if len(vals) == 0 {
+		if f := ctx.Command.lookupFlag(name, false); f != nil {
+			return f.Default
+		}
+		return ""
+	}
+	return vals[len(vals)-1]
+}
+
+// StringSlice returns every value supplied for a repeatable flag
+func (ctx *Context) StringSlice(name string) []string {
+	vals := ctx.values[name]
+	result := make([]string, len(vals))
+	copy(result, vals)
+	return result
+}
+
+// Bool returns the value of a boolean flag
+func (ctx *Context) Bool(name string) bool {
+	val, _ := strconv.ParseBool(ctx.String(name))
+	return val
+}
+
+// Count returns the number of times a boolean flag was set, e.g. 2 for -v -v (or -vv)
+func (ctx *Context) Count(name string) int {
+	count := 0
+	for _, curVal := range ctx.values[name] {
+		if val, _ := strconv.ParseBool(curVal); val {
+			count++
+		}
+	}
+	return count
+}
+
+// IsSet determines if a flag was supplied on the command line
+func (ctx *Context) IsSet(name string) bool {
+	_, ok := ctx.values[name]
+	return ok
+}
+
+// expandShortFlags - split a group of short flags (e.g. "vq") into boolean flags. Every letter must be a boolean flag.
+func (c *Command) expandShortFlags(group string) ([]*Flag, bool) {
+	flags := make([]*Flag, 0, len(group))
+	for _, curLetter := range group {
+		flag := c.lookupFlag(string(curLetter), true)
+		if flag == nil || flag.Kind != BoolFlag {
+			return nil, false
+		}
+		flags = append(flags, flag)
+	}
+	return flags, true
+}
+
+// parse walks the arguments, descending into subcommands and collecting flags and positional arguments
+func (c *Command) parse(args []string) (*Context, bool, error) {
+	ctx := &Context{Command: c, values: make(map[string][]string)}
+	showHelp := false
+	positionalOnly := false
+
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		if positionalOnly || arg == "-" || !strings.HasPrefix(arg, "-") {
+			// The first positional argument may select a subcommand
+			if !positionalOnly && len(ctx.Args) == 0 && len(ctx.Command.Subcommands) > 0 {
+				sub := ctx.Command.findSubcommand(arg)
+				if sub != nil {
+					ctx.Command = sub
+					continue
+				}
+				if ctx.Command.Run == nil {
+					return ctx, showHelp, NewUsageError(ctx, "unknown command \"%s\" for \"%s\"", arg, ctx.Command.Path())
+				}
+			}
+			ctx.Args = append(ctx.Args, arg)
+			continue
+		}
+		if arg == "--" {
+			positionalOnly = true
+			continue
+		}
+		if arg == "-h" || arg == "--help" {
+			showHelp = true
+			continue
+		}
+
+		// Support -f value, -f=value, --flag value and --flag=value
+		isShort := !strings.HasPrefix(arg, "--")
+		name := strings.TrimLeft(arg, "-")
+		value := ""
+		hasValue := false
+		if eqIdx := strings.Index(name, "="); eqIdx >= 0 {
+			value = name[eqIdx+1:]
+			name = name[:eqIdx]
+			hasValue = true
+		}
+		flag := ctx.Command.lookupFlag(name, isShort)
+		if flag == nil && isShort && !hasValue && len(name) > 1 {
+			// Combined short boolean flags, e.g. -vv
+			if expanded, ok := ctx.Command.expandShortFlags(name); ok {
+				for _, curFlag := range expanded {
+					ctx.values[curFlag.Name] = append(ctx.values[curFlag.Name], "true")
+				}
+				continue
+			}
+		}
+		if flag == nil {
+			return ctx, showHelp, NewUsageError(ctx, "unknown flag: %s", arg)
+		}
+		if flag.Kind == BoolFlag {
+			if !hasValue {
+				value = "true"
+			} else if _, err := strconv.ParseBool(value); err != nil {
+				return ctx, showHelp, NewUsageError(ctx, "invalid value for %s: %s", arg, value)
+			}
+		} else if !hasValue {
+			if idx+1 >= len(args) {
+				return ctx, showHelp, NewUsageError(ctx, "flag needs a value: %s", arg)
+			}
+			idx++
+			value = args[idx]
+		}
+		ctx.values[flag.Name] = append(ctx.values[flag.Name], value)
+	}
+	return ctx, showHelp, nil
+}
+
+// validate - ensure required flags and the positional argument count are satisfied
+func (ctx *Context) validate() error {
+	if ctx.Command.Run == nil {
+		return NewUsageError(ctx, "a subcommand is required for \"%s\"", ctx.Command.Path())
+	}
+	for _, curFlag := range ctx.Command.allFlags() {
+		if curFlag.Required && !ctx.IsSet(curFlag.Name) {
+			return NewUsageError(ctx, "required flag not set: --%s", curFlag.Name)
+		}
+	}
+	if len(ctx.Args) < ctx.Command.MinArgs {
+		return NewUsageError(ctx, "\"%s\" requires at least %d argument(s)", ctx.Command.Path(), ctx.Command.MinArgs)
+	}
+	if ctx.Command.MaxArgs >= 0 && len(ctx.Args) > ctx.Command.MaxArgs {
+		return NewUsageError(ctx, "\"%s\" accepts at most %d argument(s)", ctx.Command.Path(), ctx.Command.MaxArgs)
+	}
+	return nil
+}
+
+// Execute parses the arguments (excluding the program name), runs the selected command, and returns the exit code
+func (c *Command) Execute(args []string, stdout io.Writer, stderr io.Writer) int {
+	ctx, showHelp, err := c.parse(args)
+	ctx.Stdout = stdout
+	ctx.Stderr = stderr
+	if err == nil && showHelp {
+		PrintHelp(stdout, ctx.Command)
+		return ExitOK
+	}
+	if err == nil {
+		err = ctx.validate()
+	}
+	if err == nil {
+		err = ctx.runBefore(ctx.Command)
+	}
+	if err == nil {
+		err = ctx.Command.Run(ctx)
+	}
+	for cur := ctx.Command; cur != nil; cur = cur.parent {
+		if cur.Finish != nil {
+			return cur.Finish(ctx, err)
+		}
+	}
+	return HandleError(ctx, err)
+}
+
+// runBefore - run the Before hooks, starting from the root of the tree
+func (ctx *Context) runBefore(c *Command) error {
+	if c == nil {
+		return nil
+	}
+	if err := ctx.runBefore(c.parent); err != nil {
+		return err
+	}
+	if c.Before != nil {
+		return c.Before(ctx)
+	}
+	return nil
+}
+
+// HandleError reports an error and converts it into an exit code
+func HandleError(ctx *Context, err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintf(ctx.Stderr, "Error: %v\n\n", usageErr)
+		cmd := usageErr.Command
+		if cmd == nil {
+			cmd = ctx.Command
+		}
+		PrintUsage(ctx.Stderr, cmd)
+		fmt.Fprintf(ctx.Stderr, "Run '%s --help' for more information.\n", cmd.Path())
+		return ExitUsage
+	}
+	fmt.Fprintf(ctx.Stderr, "Error: %v\n", err)
+	var exitErr *ExitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitError
+}
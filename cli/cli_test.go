@@ -0,0 +1,145 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestCommandTree(ran **Context) *Command {
+	root := &Command{Name: "tool", Flags: []*Flag{{Name: "project", Short: "p"}, {Name: "verbose", Short: "v", Kind: BoolFlag}}}
+	run := func(ctx *Context) error {
+		*ran = ctx
+		return nil
+	}
+	apply := &Command{
+		Name: "apply",
+		Flags: []*Flag{
+			{Name: "add", Short: "a", Kind: StringSliceFlag},
+			{Name: "source", Short: "s", Required: true},
+			{Name: "sync", Kind: BoolFlag},
+		},
+		Run: run,
+	}
+	state := &Command{Name: "state"}
+	state.AddCommand(&Command{Name: "show", MinArgs: 1, MaxArgs: 1, Run: run})
+	fail := &Command{Name: "fail", Run: func(ctx *Context) error { return errors.New("boom") }}
+	root.AddCommand(apply, state, fail, NewHelpCommand(root), NewCompletionCommand(root))
+	return root
+}
+
+func Test_ExecuteParsesFlags(t *testing.T) {
+	var ran *Context
+	root := getTestCommandTree(&ran)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	code := root.Execute([]string{"-p", "proj.yaml", "apply", "-a", "one.yaml", "--add=two.yaml", "--source=state.yaml", "--sync"}, stdout, stderr)
+	assert.Equal(t, ExitOK, code)
+	assert.Equal(t, "apply", ran.Command.Name)
+	assert.Equal(t, []string{"one.yaml", "two.yaml"}, ran.StringSlice("add"))
+	assert.Equal(t, "state.yaml", ran.String("source"))
+	assert.Equal(t, "proj.yaml", ran.String("project"))
+	assert.True(t, ran.Bool("sync"))
+
+	ran = nil
+	code = root.Execute([]string{"apply", "-s", "state.yaml", "--sync=false"}, stdout, stderr)
+	assert.Equal(t, ExitOK, code)
+	assert.False(t, ran.Bool("sync"))
+	assert.False(t, ran.IsSet("add"))
+
+	code = root.Execute([]string{"-vv", "apply", "-s", "state.yaml", "-v"}, stdout, stderr)
+	assert.Equal(t, ExitOK, code)
+	assert.Equal(t, 3, ran.Count("verbose"))
+	assert.Equal(t, ExitUsage, root.Execute([]string{"-vp", "apply", "-s", "state.yaml"}, stdout, stderr))
+
+	code = root.Execute([]string{"state", "show", "component.web.app:welcome"}, stdout, stderr)
+	assert.Equal(t, ExitOK, code)
+	assert.Equal(t, []string{"component.web.app:welcome"}, ran.Args)
+}
+
+func Test_ExecuteExitCodes(t *testing.T) {
+	var ran *Context
+	root := getTestCommandTree(&ran)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	// Usage errors: unknown command / flag, missing value, missing required flag, wrong number of args
+	assert.Equal(t, ExitUsage, root.Execute([]string{"bogus"}, stdout, stderr))
+	assert.Equal(t, ExitUsage, root.Execute([]string{"apply", "-s", "x", "--bogus"}, stdout, stderr))
+	assert.Equal(t, ExitUsage, root.Execute([]string{"apply", "-s"}, stdout, stderr))
+	assert.Equal(t, ExitUsage, root.Execute([]string{"apply"}, stdout, stderr))
+	assert.Equal(t, ExitUsage, root.Execute([]string{"state"}, stdout, stderr))
+	assert.Equal(t, ExitUsage, root.Execute([]string{"state", "show"}, stdout, stderr))
+	assert.Contains(t, stderr.String(), "required flag not set: --source")
+	assert.Nil(t, ran)
+
+	// Runtime errors
+	assert.Equal(t, ExitError, root.Execute([]string{"fail"}, stdout, stderr))
+	assert.Equal(t, 3, HandleError(&Context{Stderr: stderr}, &ExitCodeError{Code: 3, Err: errors.New("custom")}))
+}
+
+func Test_Help(t *testing.T) {
+	var ran *Context
+	root := getTestCommandTree(&ran)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	assert.Equal(t, ExitOK, root.Execute([]string{"apply", "--help"}, stdout, stderr))
+	assert.Contains(t, stdout.String(), "Usage: tool apply [flags]")
+	assert.Contains(t, stdout.String(), "-s, --source <value>")
+	assert.Contains(t, stdout.String(), "Global flags:")
+	assert.Nil(t, ran)
+
+	stdout.Reset()
+	assert.Equal(t, ExitOK, root.Execute([]string{"help", "state"}, stdout, stderr))
+	assert.Contains(t, stdout.String(), "Usage: tool state <command>")
+	assert.Contains(t, stdout.String(), "show")
+
+	stdout.Reset()
+	assert.Equal(t, ExitOK, root.Execute([]string{"completion", "bash"}, stdout, stderr))
+	assert.Contains(t, stdout.String(), "complete -F _tool tool")
+	assert.Contains(t, stdout.String(), "\"tool state\") COMPREPLY=($(compgen -W \"show --project --verbose --help\"")
+}
+
+func Test_BeforeAndFinish(t *testing.T) {
+	var ran *Context
+	root := getTestCommandTree(&ran)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	var finished error
+	root.Before = func(ctx *Context) error {
+		if ctx.String("project") == "reject" {
+			return NewUsageError(ctx, "rejected")
+		}
+		return nil
+	}
+	root.Finish = func(ctx *Context, err error) int {
+		finished = err
+		return HandleError(ctx, err)
+	}
+
+	assert.Equal(t, ExitOK, root.Execute([]string{"apply", "-s", "x"}, stdout, stderr))
+	assert.NotNil(t, ran)
+	assert.Nil(t, finished)
+
+	ran = nil
+	assert.Equal(t, ExitUsage, root.Execute([]string{"-p", "reject", "apply", "-s", "x"}, stdout, stderr))
+	assert.Nil(t, ran)
+	assert.EqualError(t, finished, "rejected")
+
+	assert.Equal(t, ExitError, root.Execute([]string{"fail"}, stdout, stderr))
+	assert.EqualError(t, finished, "boom")
+}
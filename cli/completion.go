@@ -0,0 +1,125 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// walkCommands visits every command in the tree, depth first
+func walkCommands(c *Command, visit func(*Command)) {
+	visit(c)
+	for _, curSub := range c.Subcommands {
+		walkCommands(curSub, visit)
+	}
+}
+
+// GenerateBashCompletion writes a bash completion script for the command tree
+func GenerateBashCompletion(w io.Writer, root *Command) {
+	funcName := "_" + strings.Replace(root.Name, "-", "_", -1)
+
+	// Every path within the tree, and every flag which takes a value
+	paths := make([]string, 0)
+	valueFlags := make(map[string]bool)
+	walkCommands(root, func(c *Command) {
+		if c != root {
+			paths = append(paths, "\""+c.Path()+"\"")
+		}
+		for _, curFlag := range c.Flags {
+			if curFlag.Kind != BoolFlag {
+				valueFlags["--"+curFlag.Name] = true
+				if len(curFlag.Short) > 0 {
+					valueFlags["-"+curFlag.Short] = true
+				}
+			}
+		}
+	})
+	valueFlagList := make([]string, 0, len(valueFlags))
+	for curFlag := range valueFlags {
+		valueFlagList = append(valueFlagList, curFlag)
+	}
+	sort.Strings(valueFlagList)
+
+	fmt.Fprintf(w, "# bash completion for %s\n", root.Name)
+	fmt.Fprintf(w, "%s() {\n", funcName)
+	fmt.Fprintln(w, "    local cur prev path word i")
+	fmt.Fprintln(w, "    cur=\"${COMP_WORDS[COMP_CWORD]}\"")
+	fmt.Fprintln(w, "    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"")
+	fmt.Fprintf(w, "    path=\"%s\"\n", root.Name)
+	fmt.Fprintln(w, "    for ((i=1; i<COMP_CWORD; i++)); do")
+	fmt.Fprintln(w, "        word=\"${COMP_WORDS[i]}\"")
+	fmt.Fprintln(w, "        case \"$path $word\" in")
+	if len(paths) > 0 {
+		fmt.Fprintf(w, "            %s) path=\"$path $word\" ;;\n", strings.Join(paths, "|"))
+	}
+	fmt.Fprintln(w, "        esac")
+	fmt.Fprintln(w, "    done")
+	if len(valueFlagList) > 0 {
+		fmt.Fprintln(w, "    case \"$prev\" in")
+		fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", strings.Join(valueFlagList, "|"))
+		fmt.Fprintln(w, "    esac")
+	}
+	fmt.Fprintln(w, "    case \"$path\" in")
+	walkCommands(root, func(c *Command) {
+		words := make([]string, 0)
+		for _, curSub := range c.Subcommands {
+			words = append(words, curSub.Name)
+		}
+		for _, curFlag := range c.allFlags() {
+			words = append(words, "--"+curFlag.Name)
+		}
+		words = append(words, "--help")
+		fmt.Fprintf(w, "        \"%s\") COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", c.Path(), strings.Join(words, " "))
+	})
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "    if [[ ${#COMPREPLY[@]} -eq 0 ]]; then")
+	fmt.Fprintln(w, "        COMPREPLY=($(compgen -f -- \"$cur\"))")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintf(w, "complete -F %s %s\n", funcName, root.Name)
+}
+
+// GenerateZshCompletion writes a zsh completion script. zsh is able to run the bash script via bashcompinit.
+func GenerateZshCompletion(w io.Writer, root *Command) {
+	fmt.Fprintf(w, "#compdef %s\n", root.Name)
+	fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit")
+	GenerateBashCompletion(w, root)
+}
+
+// NewCompletionCommand creates a 'completion <shell>' command for the given command tree
+func NewCompletionCommand(root *Command) *Command {
+	return &Command{
+		Name:      "completion",
+		Short:     "Generate a shell completion script (bash or zsh)",
+		Long:      "Generate a shell completion script. For example: source <(" + root.Name + " completion bash)",
+		ArgsUsage: "<bash|zsh>",
+		MinArgs:   1,
+		MaxArgs:   1,
+		Run: func(ctx *Context) error {
+			switch ctx.Args[0] {
+			case "bash":
+				GenerateBashCompletion(ctx.Stdout, root)
+			case "zsh":
+				GenerateZshCompletion(ctx.Stdout, root)
+			default:
+				return NewUsageError(ctx, "unsupported shell: %s", ctx.Args[0])
+			}
+			return nil
+		},
+	}
+}
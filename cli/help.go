@@ -0,0 +1,133 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// PrintUsage prints the one line usage summary for a command
+func PrintUsage(w io.Writer, c *Command) {
+	usage := "Usage: " + c.Path()
+	if len(c.Subcommands) > 0 {
+		usage += " <command>"
+	}
+	if len(c.allFlags()) > 0 {
+		usage += " [flags]"
+	}
+	if len(c.ArgsUsage) > 0 {
+		usage += " " + c.ArgsUsage
+	}
+	fmt.Fprintln(w, usage)
+}
+
+func flagSyntax(f *Flag) string {
+	syntax := "    "
+	if len(f.Short) > 0 {
+		syntax = "-" + f.Short + ", "
+	}
+	syntax += "--" + f.Name
+	if f.Kind != BoolFlag {
+		valueName := f.ValueName
+		if len(valueName) == 0 {
+			valueName = "value"
+		}
+		syntax += " <" + valueName + ">"
+	}
+	return syntax
+}
+
+func flagDescription(f *Flag) string {
+	desc := f.Usage
+	if f.Kind == StringSliceFlag {
+		desc += " (repeatable)"
+	}
+	if f.Required {
+		desc += " (required)"
+	}
+	if len(f.Default) > 0 && f.Kind != BoolFlag {
+		desc += fmt.Sprintf(" (default \"%s\")", f.Default)
+	}
+	return desc
+}
+
+func printFlags(w io.Writer, title string, flags []*Flag) {
+	if len(flags) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", title)
+	tw := tabwriter.NewWriter(w, 0, 4, 3, ' ', 0)
+	for _, curFlag := range flags {
+		fmt.Fprintf(tw, "  %s\t%s\n", flagSyntax(curFlag), flagDescription(curFlag))
+	}
+	tw.Flush()
+}
+
+// PrintHelp prints the full help text for a command
+func PrintHelp(w io.Writer, c *Command) {
+	PrintUsage(w, c)
+	description := c.Long
+	if len(description) == 0 {
+		description = c.Short
+	}
+	if len(description) > 0 {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(description))
+	}
+
+	if len(c.Subcommands) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		tw := tabwriter.NewWriter(w, 0, 4, 3, ' ', 0)
+		for _, curSub := range c.Subcommands {
+			fmt.Fprintf(tw, "  %s\t%s\n", curSub.Name, curSub.Short)
+		}
+		tw.Flush()
+	}
+
+	printFlags(w, "Flags", c.Flags)
+	inherited := make([]*Flag, 0)
+	for cur := c.parent; cur != nil; cur = cur.parent {
+		inherited = append(inherited, cur.Flags...)
+	}
+	printFlags(w, "Global flags", inherited)
+
+	if len(c.Subcommands) > 0 {
+		fmt.Fprintf(w, "\nRun '%s <command> --help' for more information about a command.\n", c.Path())
+	}
+}
+
+// NewHelpCommand creates a 'help [command...]' command for the given command tree
+func NewHelpCommand(root *Command) *Command {
+	return &Command{
+		Name:      "help",
+		Short:     "Show help for a command",
+		ArgsUsage: "[command...]",
+		MaxArgs:   -1,
+		Run: func(ctx *Context) error {
+			target := root
+			for _, curName := range ctx.Args {
+				sub := target.findSubcommand(curName)
+				if sub == nil {
+					return NewUsageError(ctx, "unknown command \"%s\" for \"%s\"", curName, target.Path())
+				}
+				target = sub
+			}
+			PrintHelp(ctx.Stdout, target)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,30 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// rezolvr-driver-docker runs the Docker Compose driver as a separate process. rezolvr starts it, and talks to it over stdin / stdout.
+package main
+
+import (
+	"fmt"
+	"os"
+	"rezolvr/drivers/docker"
+	"rezolvr/pluginrpc"
+)
+
+func main() {
+	if err := pluginrpc.Serve(docker.Driver{}, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "rezolvr-driver-docker: %v\n", err)
+		os.Exit(1)
+	}
+}
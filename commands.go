@@ -0,0 +1,641 @@
+package main
+
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rezolvr/cli"
+	"rezolvr/config"
+	"rezolvr/drivers"
+	"rezolvr/logging"
+	"rezolvr/model"
+	"rezolvr/report"
+	"rezolvr/templates"
+	"rezolvr/utils"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	xmlexport "rezolvr/exports/xmlexport"
+)
+
+// The current version of rezolvr
+const version = "0.0.1"
+
+// cmdReport collects the results of the command being run, for '--output json'
+var cmdReport = report.New("rezolvr", version)
+
+// jsonOutput is set when the results are reported as a JSON document
+var jsonOutput = false
+
+// cfg is the effective configuration, built from the configuration files, environment variables and flags
+var cfg *config.Config
+
+// projectManifest is the project's rezolvr.yaml, when one was found
+var projectManifest *utils.Manifest
+var projectFile string
+
+// Flags shared by several commands
+var projectFlag = &cli.Flag{Name: "project", Short: "p", ValueName: "file", Usage: "Project manifest (defaults to ./" + utils.ManifestFileName + " when present)"}
+var addComponentFlag = &cli.Flag{Name: "add-component", Short: "a", ValueName: "file|dir|glob", Kind: cli.StringSliceFlag, Usage: "Component file(s) to add or update"}
+var deleteComponentFlag = &cli.Flag{Name: "delete-component", Short: "d", ValueName: "component id", Kind: cli.StringSliceFlag, Usage: "Component to remove from the state, e.g. component.web.app:welcome"}
+var environmentFlag = &cli.Flag{Name: "environment", Short: "e", ValueName: "file", Kind: cli.StringSliceFlag, Usage: "Environment file(s)"}
+var stateFlag = &cli.Flag{Name: "source", Short: "s", ValueName: "file", Usage: "State file; created if it doesn't exist"}
+var outputDirFlag = &cli.Flag{Name: "output-dir", Short: "o", ValueName: "dir", Usage: "Directory for the generated files (default \"" + config.DefaultOutputDir + "\")"}
+var outputTarFlag = &cli.Flag{Name: "output-tar", ValueName: "file", Usage: "Write the generated files into a tarball instead of the output directory (\"-\" for stdout)"}
+var outputStdoutFlag = &cli.Flag{Name: "output-stdout", Kind: cli.BoolFlag, Usage: "Write the generated files to stdout, as YAML documents, instead of the output directory"}
+var outputFlag = &cli.Flag{Name: "output", ValueName: "text|json", Default: "text", Usage: "Output format. With json, a single JSON document is written to stdout and logs go to stderr"}
+var verboseFlag = &cli.Flag{Name: "verbose", Short: "v", Kind: cli.BoolFlag, Usage: "Log more detail: -v for debug messages, -vv for trace messages"}
+var quietFlag = &cli.Flag{Name: "quiet", Short: "q", Kind: cli.BoolFlag, Usage: "Only log errors"}
+var logLevelFlag = &cli.Flag{Name: "log-level", ValueName: "level", Usage: "Log level: error, warn, info, debug or trace (default \"info\")"}
+var pluginDirFlag = &cli.Flag{Name: "plugin-dir", ValueName: "dir", Usage: "Directory containing the plugins (default \"$HOME/.rezolvr/plugins/\")"}
+var strictFlag = &cli.Flag{Name: "strict", Kind: cli.BoolFlag, Usage: "Treat warnings (e.g. stale environment properties) as errors"}
+var logFormatFlag = &cli.Flag{Name: "log-format", ValueName: "text|json", Default: logging.FormatText, Usage: "Format of the log messages written to stderr"}
+var syncEnvFlag = &cli.Flag{Name: "sync-env", Kind: cli.BoolFlag, Usage: "Treat the environment file(s) as authoritative; drop properties missing from them"}
+
+func newRootCommand() *cli.Command {
+	root := &cli.Command{
+		Name:  "rezolvr",
+		Short: "Resolve complex deployment details in a containerized, microservices-based world",
+		Long: `Resolve complex deployment details in a containerized, microservices-based world.
+
+Exit codes: 0 on success, 1 when a command fails, 2 when the command line is invalid.`,
+		Flags:  []*cli.Flag{projectFlag, outputFlag, verboseFlag, quietFlag, logLevelFlag, logFormatFlag, pluginDirFlag, strictFlag},
+		Before: beforeCommand,
+		Finish: finishCommand,
+	}
+
+	resolveFlags := []*cli.Flag{addComponentFlag, deleteComponentFlag, environmentFlag, stateFlag, syncEnvFlag}
+	applyCmd := &cli.Command{
+		Name:  "apply",
+		Short: "Resolve components, generate platform-specific output and update the state",
+		Flags: append(append([]*cli.Flag{}, resolveFlags...), outputDirFlag, outputTarFlag, outputStdoutFlag),
+		Run: func(ctx *cli.Context) error {
+			cliArgs, err := prepareArgs(ctx, true, true)
+			if err == nil {
+				err = report.WithCode(report.CodeLoad, loadRezolvrFiles(cliArgs))
+			}
+			if err == nil {
+				err = loadDriver()
+			}
+			if err == nil {
+				defer closeDriver()
+				err = applyUpdatedComponents(cliArgs)
+			}
+			return err
+		},
+	}
+	whatIfCmd := &cli.Command{
+		Name:  "whatif",
+		Short: "Show what 'apply' would change, without generating output or saving the state",
+		Flags: resolveFlags,
+		Run: func(ctx *cli.Context) error {
+			cliArgs, err := prepareArgs(ctx, true, true)
+			if err == nil {
+				err = report.WithCode(report.CodeLoad, loadRezolvrFiles(cliArgs))
+			}
+			if err == nil {
+				err = whatIfUpdatedComponents(cliArgs)
+			}
+			return err
+		},
+	}
+	refreshCmd := &cli.Command{
+		Name:  "refresh",
+		Short: "Re-resolve every component in the state and regenerate the output",
+		Flags: []*cli.Flag{environmentFlag, stateFlag, outputDirFlag, outputTarFlag, outputStdoutFlag, syncEnvFlag},
+		Run: func(ctx *cli.Context) error {
+			cliArgs, err := prepareArgs(ctx, true, true)
+			if err == nil {
+				err = report.WithCode(report.CodeLoad, loadRezolvrFiles(cliArgs))
+			}
+			if err == nil {
+				err = loadDriver()
+			}
+			if err == nil {
+				defer closeDriver()
+				err = refreshAllComponents(cliArgs)
+			}
+			return err
+		},
+	}
+	validateCmd := &cli.Command{
+		Name:  "validate",
+		Short: "Check that the state is consistent and that the changed components, and those they impact, can be resolved",
+		Flags: resolveFlags,
+		Run: func(ctx *cli.Context) error {
+			cliArgs, err := prepareArgs(ctx, true, true)
+			if err == nil {
+				err = report.WithCode(report.CodeLoad, loadRezolvrFiles(cliArgs))
+			}
+			if err == nil {
+				err = validateComponents(cliArgs)
+			}
+			return err
+		},
+	}
+	exportCmd := &cli.Command{
+		Name:  "export",
+		Short: "Export the state as a diagram (draw.io XML)",
+		Flags: []*cli.Flag{
+			stateFlag,
+			{Name: "export", Short: "x", ValueName: "file", Required: true, Usage: "File to export the diagram to"},
+		},
+		Run: func(ctx *cli.Context) error {
+			cliArgs, err := prepareArgs(ctx, true, false)
+			if err != nil {
+				return err
+			}
+			loadedState, err := loadStateFile(cliArgs.StateFile)
+			if err != nil {
+				return report.WithCode(report.CodeLoad, err)
+			}
+			err = xmlexport.ExportState(loadedState, cliArgs.ExportFile)
+			if err != nil {
+				return report.WithCode(report.CodeExport, err)
+			}
+			cmdReport.Files = append(cmdReport.Files, cliArgs.ExportFile)
+			return nil
+		},
+	}
+
+	root.AddCommand(applyCmd, whatIfCmd, refreshCmd, validateCmd, exportCmd, newStateCommand(), newPluginsCommand(), newTemplatesCommand(),
+		newConfigCommand(),
+		&cli.Command{
+			Name:  "version",
+			Short: "Print the version of rezolvr",
+			Run: func(ctx *cli.Context) error {
+				if !jsonOutput {
+					fmt.Fprintf(ctx.Stdout, "rezolvr version %s\n", version)
+				}
+				return nil
+			},
+		})
+	root.AddCommand(cli.NewCompletionCommand(root), cli.NewHelpCommand(root))
+	return root
+}
+
+func newStateCommand() *cli.Command {
+	stateCmd := &cli.Command{
+		Name:  "state",
+		Short: "Inspect the state of the system",
+	}
+	listCmd := &cli.Command{
+		Name:  "list",
+		Short: "List the components within the state",
+		Flags: []*cli.Flag{stateFlag},
+		Run: func(ctx *cli.Context) error {
+			cliArgs, err := prepareArgs(ctx, true, false)
+			if err != nil {
+				return err
+			}
+			loadedState, err := loadStateFile(cliArgs.StateFile)
+			if err != nil {
+				return report.WithCode(report.CodeLoad, err)
+			}
+			componentIDs := make([]string, 0, len(loadedState.Components))
+			for k := range loadedState.Components {
+				componentIDs = append(componentIDs, k)
+			}
+			sort.Strings(componentIDs)
+			cmdReport.AddComponents(loadedState.Components, model.SecretValues(loadedState.Components))
+			if jsonOutput {
+				return nil
+			}
+			tw := tabwriter.NewWriter(ctx.Stdout, 0, 4, 3, ' ', 0)
+			fmt.Fprintln(tw, "COMPONENT\tPROVIDES\tNEEDS")
+			for _, k := range componentIDs {
+				curComponent := loadedState.Components[k]
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", k, joinResourceIDs(curComponent.Provides), joinResourceIDs(curComponent.Needs))
+			}
+			return tw.Flush()
+		},
+	}
+	showCmd := &cli.Command{
+		Name:      "show",
+		Short:     "Show a single component within the state",
+		ArgsUsage: "<component id>",
+		MinArgs:   1,
+		MaxArgs:   1,
+		Flags:     []*cli.Flag{stateFlag},
+		Run: func(ctx *cli.Context) error {
+			cliArgs, err := prepareArgs(ctx, true, false)
+			if err != nil {
+				return err
+			}
+			loadedState, err := loadStateFile(cliArgs.StateFile)
+			if err != nil {
+				return report.WithCode(report.CodeLoad, err)
+			}
+			curComponent, ok := loadedState.Components[ctx.Args[0]]
+			if !ok {
+				return report.WithCode(report.CodeNotFound, fmt.Errorf("component not found in the state: %s", ctx.Args[0]))
+			}
+			cmdReport.AddComponents(map[string]*model.Component{ctx.Args[0]: curComponent}, model.SecretValues(loadedState.Components))
+			if jsonOutput {
+				return nil
+			}
+			content, err := model.PrepComponentForPersistence(curComponent)
+			if err != nil {
+				return err
+			}
+			_, err = ctx.Stdout.Write(content)
+			return err
+		},
+	}
+	stateCmd.AddCommand(listCmd, showCmd)
+	return stateCmd
+}
+
+func newPluginsCommand() *cli.Command {
+	pluginsCmd := &cli.Command{
+		Name:  "plugins",
+		Short: "Manage the plugins used to generate platform-specific output",
+	}
+	listCmd := &cli.Command{
+		Name:  "list",
+		Short: "List the built-in drivers, and the drivers found in the plugin directory and on the PATH",
+		Run: func(ctx *cli.Context) error {
+			if _, err := prepareArgs(ctx, false, false); err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(ctx.Stdout, 0, 4, 3, ' ', 0)
+			fmt.Fprintln(tw, "DRIVER\tTYPE\tLOCATION")
+			for _, curDriver := range utils.FindDrivers(pluginDir) {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", curDriver.Name, curDriver.Kind, curDriver.Location)
+			}
+			return tw.Flush()
+		},
+	}
+	showCmd := &cli.Command{
+		Name:      "show",
+		Short:     "Start a driver, and show its version and capabilities",
+		ArgsUsage: "<driver>",
+		MinArgs:   1,
+		MaxArgs:   1,
+		Run: func(ctx *cli.Context) error {
+			if _, err := prepareArgs(ctx, false, false); err != nil {
+				return err
+			}
+			driverName = ctx.Args[0]
+			if err := loadDriver(); err != nil {
+				return err
+			}
+			defer closeDriver()
+			capabilities := rezolvrPlugin.Capabilities()
+			cmdReport.Driver = &report.Driver{Name: rezolvrPlugin.Name(), Version: rezolvrPlugin.Version(), Kind: rezolvrPluginInfo.Kind,
+				Location: rezolvrPluginInfo.Location, APIVersion: capabilities.APIVersion, ResourceTypes: capabilities.ResourceTypes,
+				OutputFormats: capabilities.OutputFormats}
+			if jsonOutput {
+				return nil
+			}
+			tw := tabwriter.NewWriter(ctx.Stdout, 0, 4, 3, ' ', 0)
+			fmt.Fprintf(tw, "Name:\t%s\n", cmdReport.Driver.Name)
+			fmt.Fprintf(tw, "Version:\t%s\n", cmdReport.Driver.Version)
+			fmt.Fprintf(tw, "Type:\t%s\n", cmdReport.Driver.Kind)
+			fmt.Fprintf(tw, "Location:\t%s\n", cmdReport.Driver.Location)
+			fmt.Fprintf(tw, "API version:\t%d\n", cmdReport.Driver.APIVersion)
+			fmt.Fprintf(tw, "Resource types:\t%s\n", strings.Join(cmdReport.Driver.ResourceTypes, ", "))
+			fmt.Fprintf(tw, "Output formats:\t%s\n", strings.Join(cmdReport.Driver.OutputFormats, ", "))
+			return tw.Flush()
+		},
+	}
+	pluginsCmd.AddCommand(listCmd, showCmd)
+	return pluginsCmd
+}
+
+// newTemplateLoader - a loader for the templates of a driver, using the same search path as the driver itself
+func newTemplateLoader(driverName string) *templates.Loader {
+	return templates.NewLoader(driverName, filepath.Join(pluginDir, driverName), drivers.Templates(driverName))
+}
+
+func newTemplatesCommand() *cli.Command {
+	templatesCmd := &cli.Command{
+		Name:  "templates",
+		Short: "Inspect and customize the templates used by the drivers",
+		Long: `Inspect and customize the templates used by the drivers. Each template is looked for in the project's
+` + templates.ProjectDir + `/<driver>/ directory, then in <pluginDir>/<driver>/templates/, and finally within the driver itself.`,
+	}
+	listCmd := &cli.Command{
+		Name:      "list",
+		Short:     "List the templates of every driver (or a single driver), and where each one comes from",
+		ArgsUsage: "[driver]",
+		MaxArgs:   1,
+		Run: func(ctx *cli.Context) error {
+			if _, err := prepareArgs(ctx, false, false); err != nil {
+				return err
+			}
+			driverNames := ctx.Args
+			if len(driverNames) == 0 {
+				for _, curDriver := range utils.FindDrivers(pluginDir) {
+					driverNames = append(driverNames, curDriver.Name)
+				}
+			}
+			for _, curDriverName := range driverNames {
+				driverTemplates, err := newTemplateLoader(curDriverName).List()
+				if err != nil {
+					return err
+				}
+				for _, curTemplate := range driverTemplates {
+					cmdReport.Templates = append(cmdReport.Templates, report.Template{Driver: curDriverName, Name: curTemplate.Name,
+						Source: curTemplate.Source, Location: curTemplate.Location})
+				}
+			}
+			if jsonOutput {
+				return nil
+			}
+			tw := tabwriter.NewWriter(ctx.Stdout, 0, 4, 3, ' ', 0)
+			fmt.Fprintln(tw, "DRIVER\tTEMPLATE\tSOURCE\tLOCATION")
+			for _, curTemplate := range cmdReport.Templates {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", curTemplate.Driver, curTemplate.Name, curTemplate.Source, curTemplate.Location)
+			}
+			return tw.Flush()
+		},
+	}
+	showCmd := &cli.Command{
+		Name:      "show",
+		Short:     "Show the template a driver would use",
+		ArgsUsage: "<driver> <template>",
+		MinArgs:   2,
+		MaxArgs:   2,
+		Run: func(ctx *cli.Context) error {
+			if _, err := prepareArgs(ctx, false, false); err != nil {
+				return err
+			}
+			curTemplate, err := newTemplateLoader(ctx.Args[0]).Load(ctx.Args[1])
+			if errors.Is(err, templates.ErrNotFound) {
+				return report.WithCode(report.CodeNotFound, err)
+			} else if err != nil {
+				return err
+			}
+			cmdReport.Templates = append(cmdReport.Templates, report.Template{Driver: ctx.Args[0], Name: curTemplate.Name,
+				Source: curTemplate.Source, Location: curTemplate.Location})
+			if jsonOutput {
+				return nil
+			}
+			logger.Infof("Using the %s template: %s", curTemplate.Source, curTemplate.Location)
+			_, err = ctx.Stdout.Write(curTemplate.Content)
+			return err
+		},
+	}
+	forceFlag := &cli.Flag{Name: "force", Kind: cli.BoolFlag, Usage: "Replace a template which has already been ejected"}
+	ejectCmd := &cli.Command{
+		Name:      "eject",
+		Short:     "Copy a template into the project, so that it can be customized",
+		Long:      "Copy a template into " + templates.ProjectDir + "/<driver>/, where it overrides the user's and the built-in templates.",
+		ArgsUsage: "<driver> <template>",
+		MinArgs:   2,
+		MaxArgs:   2,
+		Flags:     []*cli.Flag{forceFlag},
+		Run: func(ctx *cli.Context) error {
+			if _, err := prepareArgs(ctx, false, false); err != nil {
+				return err
+			}
+			fileName, err := newTemplateLoader(ctx.Args[0]).Eject(ctx.Args[1], ctx.Bool(forceFlag.Name))
+			if errors.Is(err, templates.ErrNotFound) {
+				return report.WithCode(report.CodeNotFound, err)
+			} else if err != nil {
+				return report.WithCode(report.CodeSave, err)
+			}
+			cmdReport.Files = append(cmdReport.Files, fileName)
+			logger.Infof("Template ejected: %s", fileName)
+			return nil
+		},
+	}
+	templatesCmd.AddCommand(listCmd, showCmd, ejectCmd)
+	return templatesCmd
+}
+
+func newConfigCommand() *cli.Command {
+	configCmd := &cli.Command{
+		Name:  "config",
+		Short: "Inspect the configuration of rezolvr",
+	}
+	showCmd := &cli.Command{
+		Name:  "show",
+		Short: "Show the effective configuration, and where each value came from",
+		Long: `Show the effective configuration, and where each value came from. Each source overrides the previous one:
+the defaults, ~/.rezolvr/config.yaml, the project's ` + utils.ManifestFileName + `, REZOLVR_* environment variables
+(e.g. REZOLVR_OUTPUTDIR), and finally the command line flags.`,
+		Run: func(ctx *cli.Context) error {
+			for _, curSetting := range cfg.Settings() {
+				cmdReport.Config = append(cmdReport.Config, report.Setting{Name: curSetting.Name, Value: curSetting.Value, Source: curSetting.Source})
+			}
+			if jsonOutput {
+				return nil
+			}
+			tw := tabwriter.NewWriter(ctx.Stdout, 0, 4, 3, ' ', 0)
+			fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+			for _, curSetting := range cfg.Settings() {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", curSetting.Name, curSetting.Value, curSetting.Source)
+			}
+			return tw.Flush()
+		},
+	}
+	configCmd.AddCommand(showCmd)
+	return configCmd
+}
+
+// beforeCommand - load the configuration, and set up the logger and the report for the selected command. With JSON output,
+// anything written to the process's stdout (e.g. by plugins) is sent to stderr, so that the report is the only thing on stdout.
+func beforeCommand(ctx *cli.Context) error {
+	if err := loadConfig(ctx); err != nil {
+		return err
+	}
+	if err := configureLogger(ctx); err != nil {
+		return err
+	}
+	switch ctx.String(outputFlag.Name) {
+	case "text":
+		jsonOutput = false
+	case "json":
+		jsonOutput = true
+		os.Stdout = os.Stderr
+	default:
+		return cli.NewUsageError(ctx, "invalid value for --%s: %s (expected text or json)", outputFlag.Name, ctx.String(outputFlag.Name))
+	}
+	cmdReport = report.New(ctx.Command.Path(), version)
+	return nil
+}
+
+// loadConfig - build the effective configuration. Each layer overrides the previous one: the defaults, the user's
+// configuration file, the project manifest, REZOLVR_* environment variables, and finally the command line flags.
+func loadConfig(ctx *cli.Context) error {
+	homeDir := os.Getenv("HOME")
+	cfg = config.New(homeDir)
+	userConfigFile := config.UserConfigFile(homeDir)
+	userSettings, err := config.LoadSettingsFile(userConfigFile)
+	if err != nil {
+		return report.WithCode(report.CodeConfig, fmt.Errorf("error loading the configuration file: %v", err))
+	}
+	cfg.ApplySettings(userSettings, userConfigFile)
+
+	// The project manifest is optional, unless it was named on the command line
+	projectFile = ctx.String(projectFlag.Name)
+	explicitProject := len(projectFile) > 0
+	if !explicitProject {
+		projectFile = utils.ManifestFileName
+	}
+	projectManifest, err = utils.LoadManifest(projectFile, explicitProject)
+	if err != nil {
+		return report.WithCode(report.CodeConfig, fmt.Errorf("error loading the project manifest: %v", err))
+	}
+	if projectManifest != nil {
+		cfg.ApplySettings(projectManifest.GetSettings(), projectFile)
+	} else {
+		projectFile = ""
+	}
+
+	cfg.ApplyEnvironment(os.LookupEnv)
+
+	flagSettings := []struct {
+		name string
+		flag *cli.Flag
+	}{
+		{config.PluginDir, pluginDirFlag},
+		{config.OutputDir, outputDirFlag},
+		{config.LogLevel, logLevelFlag},
+		{config.Strict, strictFlag},
+	}
+	for _, curFlagSetting := range flagSettings {
+		if ctx.IsSet(curFlagSetting.flag.Name) {
+			cfg.Set(curFlagSetting.name, ctx.String(curFlagSetting.flag.Name), "flag --"+curFlagSetting.flag.Name)
+		}
+	}
+	return report.WithCode(report.CodeConfig, cfg.Validate())
+}
+
+// configureLogger - create the logger from the configured log level and the verbosity flags. It's shared with the model, resolver and plugins.
+func configureLogger(ctx *cli.Context) error {
+	level, err := logging.ParseLevel(cfg.Get(config.LogLevel))
+	if err != nil {
+		return err
+	}
+	verbosity := ctx.Count(verboseFlag.Name)
+	if verbosity > 0 && ctx.Bool(quietFlag.Name) {
+		return cli.NewUsageError(ctx, "--%s and --%s cannot be used together", verboseFlag.Name, quietFlag.Name)
+	}
+	if ctx.Bool(quietFlag.Name) {
+		level = logging.LevelError
+	}
+	for ; verbosity > 0 && level < logging.LevelTrace; verbosity-- {
+		level++
+	}
+	newLogger, err := logging.New(ctx.Stderr, level, ctx.String(logFormatFlag.Name))
+	if err != nil {
+		return cli.NewUsageError(ctx, "%v", err)
+	}
+	logger = newLogger
+	model.SetLogger(logger)
+	return nil
+}
+
+// finishCommand - report any error, and write the JSON report when requested
+func finishCommand(ctx *cli.Context, err error) int {
+	code := cli.HandleError(ctx, err)
+	if ctx.String(outputFlag.Name) != "json" {
+		return code
+	}
+	if cmdReport.Command != ctx.Command.Path() {
+		// The command line was rejected before the command could start
+		cmdReport = report.New(ctx.Command.Path(), version)
+	}
+	cmdReport.Success = err == nil
+	if err != nil {
+		var usageErr *cli.UsageError
+		if errors.As(err, &usageErr) {
+			cmdReport.AddErrorWithCode(report.CodeUsage, err)
+		} else {
+			cmdReport.AddError(err)
+		}
+	}
+	if writeErr := cmdReport.Write(ctx.Stdout); writeErr != nil {
+		fmt.Fprintf(ctx.Stderr, "Error: unable to write the report: %v\n", writeErr)
+		return cli.ExitError
+	}
+	return code
+}
+
+// prepareArgs converts the parsed flags into command line arguments, filling in the gaps from the project manifest
+func prepareArgs(ctx *cli.Context, needsState bool, needsEnvironment bool) (*utils.CmdLineArgs, error) {
+	logger.Debugf("rezolvr version: %s", version)
+	cliArgs := &utils.CmdLineArgs{
+		Command:            ctx.Command.Name,
+		ComponentsToAdd:    ctx.StringSlice(addComponentFlag.Name),
+		ComponentsToDelete: ctx.StringSlice(deleteComponentFlag.Name),
+		EnvFiles:           ctx.StringSlice(environmentFlag.Name),
+		StateFile:          ctx.String(stateFlag.Name),
+		ExportFile:         ctx.String("export"),
+		OutputDir:          cfg.Get(config.OutputDir),
+		OutputTar:          ctx.String(outputTarFlag.Name),
+		OutputStdout:       ctx.Bool(outputStdoutFlag.Name),
+		SyncEnvironment:    ctx.Bool(syncEnvFlag.Name),
+		ProjectFile:        projectFile,
+		Strict:             cfg.GetBool(config.Strict),
+	}
+
+	// A project manifest supplies any values missing from the command line
+	if projectManifest != nil {
+		logger.Infof("Using project manifest: %s", projectFile)
+		projectManifest.ApplyTo(cliArgs)
+	}
+	if len(cliArgs.EnvFiles) == 0 && len(cfg.Get(config.DefaultEnvironment)) > 0 {
+		cliArgs.EnvFiles = []string{cfg.Get(config.DefaultEnvironment)}
+	}
+
+	pluginDir = cfg.Get(config.PluginDir)
+	logger.Debugf("Plugin directory: %s", pluginDir)
+
+	// The generated files may go to one place only, and stdout is reserved for the report when it's JSON
+	if len(cliArgs.OutputTar) > 0 && cliArgs.OutputStdout {
+		return nil, cli.NewUsageError(ctx, "--%s and --%s cannot be used together", outputTarFlag.Name, outputStdoutFlag.Name)
+	}
+	if jsonOutput && (cliArgs.OutputStdout || cliArgs.OutputTar == "-") {
+		return nil, cli.NewUsageError(ctx, "the generated files cannot be written to stdout with --%s json", outputFlag.Name)
+	}
+
+	// The state and environment may come from either the command line or the manifest, so they're checked here
+	if needsState && len(cliArgs.StateFile) == 0 {
+		return nil, cli.NewUsageError(ctx, "required flag not set: --%s (or 'state' in %s)", stateFlag.Name, utils.ManifestFileName)
+	}
+	if needsEnvironment && len(cliArgs.EnvFiles) == 0 {
+		return nil, cli.NewUsageError(ctx, "required flag not set: --%s (or 'environments' in %s, or the '%s' setting)", environmentFlag.Name, utils.ManifestFileName, config.DefaultEnvironment)
+	}
+	return cliArgs, nil
+}
+
+func loadStateFile(stateFile string) (*model.State, error) {
+	content, err := utils.LoadFile(stateFile, false)
+	if err != nil {
+		return nil, fmt.Errorf("error loading state file: %v", err)
+	}
+	loadedState, err := model.LoadState(content)
+	if err != nil {
+		return nil, fmt.Errorf("error loading state: %v", err)
+	}
+	return loadedState, nil
+}
+
+func joinResourceIDs(resources map[string]*model.Resource) string {
+	ids := make([]string, 0, len(resources))
+	for k := range resources {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
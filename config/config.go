@@ -0,0 +1,215 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"rezolvr/logging"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Names of the settings, as used in the configuration files
+const (
+	PluginDir          = "pluginDir"
+	OutputDir          = "outputDir"
+	StateBackend       = "stateBackend"
+	LogLevel           = "logLevel"
+	Strict             = "strict"
+	DefaultEnvironment = "defaultEnvironment"
+)
+
+// Names lists every setting, in the order they're displayed
+var Names = []string{PluginDir, OutputDir, StateBackend, LogLevel, Strict, DefaultEnvironment}
+
+// DefaultOutputDir is used when no output directory has been configured
+const DefaultOutputDir = "./out/"
+
+// StateBackendFile stores the state in a local YAML file. The stateBackend setting is reserved for other backends; until
+// one exists, "file" is the only value which is accepted, and the setting has no effect.
+const StateBackendFile = "file"
+
+// SourceDefault is the source of a setting which hasn't been configured anywhere
+const SourceDefault = "default"
+
+// EnvVarPrefix is prepended to a setting's upper-cased name to form its environment variable, e.g. REZOLVR_PLUGINDIR
+const EnvVarPrefix = "REZOLVR_"
+
+// Settings holds the values found in a single configuration file. Empty values are not set.
+type Settings struct {
+	PluginDir          string `yaml:"pluginDir,omitempty"`
+	OutputDir          string `yaml:"outputDir,omitempty"`
+	StateBackend       string `yaml:"stateBackend,omitempty"`
+	LogLevel           string `yaml:"logLevel,omitempty"`
+	Strict             *bool  `yaml:"strict,omitempty"`
+	DefaultEnvironment string `yaml:"defaultEnvironment,omitempty"`
+}
+
+// Values converts the settings into a map of setting name to value, skipping those which aren't set
+func (s *Settings) Values() map[string]string {
+	values := map[string]string{
+		PluginDir:          s.PluginDir,
+		OutputDir:          s.OutputDir,
+		StateBackend:       s.StateBackend,
+		LogLevel:           s.LogLevel,
+		DefaultEnvironment: s.DefaultEnvironment,
+	}
+	if s.Strict != nil {
+		values[Strict] = strconv.FormatBool(*s.Strict)
+	}
+	for k, v := range values {
+		if len(v) == 0 {
+			delete(values, k)
+		}
+	}
+	return values
+}
+
+// ResolvePaths makes any relative paths within the settings relative to a base directory
+func (s *Settings) ResolvePaths(baseDir string) {
+	for _, curPath := range []*string{&s.PluginDir, &s.OutputDir, &s.DefaultEnvironment} {
+		if len(*curPath) > 0 && !filepath.IsAbs(*curPath) {
+			*curPath = filepath.Join(baseDir, *curPath)
+		}
+	}
+}
+
+// UserConfigFile returns the location of the user's configuration file
+func UserConfigFile(homeDir string) string {
+	return filepath.Join(homeDir, ".rezolvr", "config.yaml")
+}
+
+// LoadSettingsFile - load a configuration file. Relative paths are relative to the file's directory.
+// A missing file results in nil settings.
+func LoadSettingsFile(fileName string) (*Settings, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	settings := &Settings{}
+	err = yaml.UnmarshalStrict(content, settings)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	settings.ResolvePaths(filepath.Dir(fileName))
+	return settings, nil
+}
+
+// Setting is a single effective value, along with where it came from
+type Setting struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// Config is the effective configuration. Each layer that's applied overrides the values of the previous layers.
+type Config struct {
+	settings map[string]*Setting
+}
+
+// New creates a configuration holding the default values
+func New(homeDir string) *Config {
+	c := &Config{settings: make(map[string]*Setting)}
+	c.Set(PluginDir, filepath.Join(homeDir, ".rezolvr", "plugins"), SourceDefault)
+	c.Set(OutputDir, DefaultOutputDir, SourceDefault)
+	c.Set(StateBackend, StateBackendFile, SourceDefault)
+	c.Set(LogLevel, logging.LevelInfo.String(), SourceDefault)
+	c.Set(Strict, "false", SourceDefault)
+	c.Set(DefaultEnvironment, "", SourceDefault)
+	return c
+}
+
+// Set overrides a single value. Directories always end with a separator, as plugins append file names to them.
+func (c *Config) Set(name string, value string, source string) {
+	if (name == PluginDir || name == OutputDir) && len(value) > 0 && !strings.HasSuffix(value, string(filepath.Separator)) {
+		value = value + string(filepath.Separator)
+	}
+	c.settings[name] = &Setting{Name: name, Value: value, Source: source}
+}
+
+// ApplySettings overrides the values with those set in a configuration file
+func (c *Config) ApplySettings(settings *Settings, source string) {
+	if settings == nil {
+		return
+	}
+	for k, v := range settings.Values() {
+		c.Set(k, v, source)
+	}
+}
+
+// EnvVarName returns the environment variable for a setting, e.g. REZOLVR_OUTPUTDIR
+func EnvVarName(name string) string {
+	return EnvVarPrefix + strings.ToUpper(name)
+}
+
+// ApplyEnvironment overrides the values with any REZOLVR_* environment variables
+func (c *Config) ApplyEnvironment(lookupEnv func(string) (string, bool)) {
+	for _, curName := range Names {
+		envVar := EnvVarName(curName)
+		if value, ok := lookupEnv(envVar); ok {
+			c.Set(curName, value, "env "+envVar)
+		}
+	}
+}
+
+// Get returns the effective value of a setting
+func (c *Config) Get(name string) string {
+	if curSetting, ok := c.settings[name]; ok {
+		return curSetting.Value
+	}
+	return ""
+}
+
+// GetBool returns the effective value of a boolean setting
+func (c *Config) GetBool(name string) bool {
+	value, _ := strconv.ParseBool(c.Get(name))
+	return value
+}
+
+// GetSetting returns the effective value of a setting, and its source
+func (c *Config) GetSetting(name string) *Setting {
+	return c.settings[name]
+}
+
+// Settings returns every setting, in display order
+func (c *Config) Settings() []*Setting {
+	settings := make([]*Setting, 0, len(Names))
+	for _, curName := range Names {
+		settings = append(settings, c.settings[curName])
+	}
+	return settings
+}
+
+// Validate ensures that every value is usable, naming the source of any invalid value
+func (c *Config) Validate() error {
+	if _, err := logging.ParseLevel(c.Get(LogLevel)); err != nil {
+		return fmt.Errorf("%v (from %s)", err, c.settings[LogLevel].Source)
+	}
+	if _, err := strconv.ParseBool(c.Get(Strict)); err != nil {
+		return fmt.Errorf("invalid value for %s: %s (from %s)", Strict, c.Get(Strict), c.settings[Strict].Source)
+	}
+	if c.Get(StateBackend) != StateBackendFile {
+		return fmt.Errorf("unsupported state backend: %s (from %s). The setting is reserved; the only backend is %s", c.Get(StateBackend), c.settings[StateBackend].Source, StateBackendFile)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConfigLayers(t *testing.T) {
+	baseDir := t.TempDir()
+	c := New("/home/user")
+	assert.Equal(t, "/home/user/.rezolvr/plugins/", c.Get(PluginDir))
+	assert.Equal(t, DefaultOutputDir, c.Get(OutputDir))
+	assert.Equal(t, SourceDefault, c.GetSetting(LogLevel).Source)
+	assert.False(t, c.GetBool(Strict))
+
+	// A missing file isn't an error
+	settings, err := LoadSettingsFile(filepath.Join(baseDir, "missing.yaml"))
+	assert.Nil(t, err)
+	assert.Nil(t, settings)
+	c.ApplySettings(settings, "missing.yaml")
+
+	userFile := filepath.Join(baseDir, "config.yaml")
+	content := "pluginDir: ./plugins\nlogLevel: debug\nstrict: true\ndefaultEnvironment: /envs/dev.yaml\n"
+	assert.Nil(t, ioutil.WriteFile(userFile, []byte(content), 0644))
+	settings, err = LoadSettingsFile(userFile)
+	assert.Nil(t, err)
+	c.ApplySettings(settings, userFile)
+	assert.Equal(t, filepath.Join(baseDir, "plugins")+"/", c.Get(PluginDir))
+	assert.Equal(t, "/envs/dev.yaml", c.Get(DefaultEnvironment))
+	assert.True(t, c.GetBool(Strict))
+
+	// Environment variables override the files, and flags (set last) override everything
+	env := map[string]string{"REZOLVR_LOGLEVEL": "trace", "REZOLVR_PLUGINDIR": "/opt/plugins"}
+	c.ApplyEnvironment(func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+	c.Set(LogLevel, "warn", "flag --log-level")
+	assert.Equal(t, &Setting{Name: PluginDir, Value: "/opt/plugins/", Source: "env REZOLVR_PLUGINDIR"}, c.GetSetting(PluginDir))
+	assert.Equal(t, &Setting{Name: LogLevel, Value: "warn", Source: "flag --log-level"}, c.GetSetting(LogLevel))
+	assert.Equal(t, userFile, c.GetSetting(Strict).Source)
+	assert.Equal(t, SourceDefault, c.GetSetting(OutputDir).Source)
+	assert.Nil(t, c.Validate())
+
+	assert.Equal(t, len(Names), len(c.Settings()))
+	assert.Equal(t, PluginDir, c.Settings()[0].Name)
+}
+
+func Test_ConfigValidation(t *testing.T) {
+	baseDir := t.TempDir()
+	badFile := filepath.Join(baseDir, "config.yaml")
+	assert.Nil(t, ioutil.WriteFile(badFile, []byte("logLevl: debug\n"), 0644))
+	_, err := LoadSettingsFile(badFile)
+	assert.NotNil(t, err)
+
+	c := New("/home/user")
+	c.Set(LogLevel, "loud", "env REZOLVR_LOGLEVEL")
+	assert.EqualError(t, c.Validate(), "unknown log level: loud (expected one of error, warn, info, debug, trace) (from env REZOLVR_LOGLEVEL)")
+
+	c = New("/home/user")
+	c.Set(StateBackend, "s3", "rezolvr.yaml")
+	assert.Contains(t, c.Validate().Error(), "unsupported state backend: s3 (from rezolvr.yaml)")
+
+	c = New("/home/user")
+	c.Set(Strict, "maybe", "flag --strict")
+	assert.NotNil(t, c.Validate())
+}
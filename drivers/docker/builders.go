@@ -0,0 +1,276 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"fmt"
+	"rezolvr/drivers"
+	"rezolvr/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// buildContext - everything a builder needs to add a provided resource to the compose file
+type buildContext struct {
+	component *model.Component
+	provides  *model.Resource
+	// provided - the resources provided by every component, which a service may refer to. Not checked when nil.
+	provided *providedResources
+}
+
+// providedResources - the resources provided by the components, which a service may refer to
+type providedResources struct {
+	// volumes - the names of the storage volumes
+	volumes map[string]bool
+	// environment - the environment.secret and environment.properties resources, by ID (e.g. environment.secret:creds)
+	environment map[string]*model.Resource
+}
+
+// builder adds a provided resource to the compose file
+type builder func(bc *buildContext, project *Project) error
+
+// builders - the resource types which are generated from the typed model. Other types fall back to a template.
+var builders = map[string]builder{
+	"service.web.app":      buildWebApp,
+	"service.db.postgres":  buildPostgres,
+	"storage.volume":       buildVolume,
+	"storage.volume-claim": buildVolumeClaim,
+}
+
+// builderTypes - the resource types with a builder, sorted
+func builderTypes() []string {
+	results := make([]string, 0, len(builders))
+	for k := range builders {
+		results = append(results, k)
+	}
+	sort.Strings(results)
+	return results
+}
+
+func (bc *buildContext) resourceID() string {
+	return drivers.ResourceID(bc.provides)
+}
+
+// param - the value of a provided param, or an empty string when it isn't set
+func (bc *buildContext) param(name string) string {
+	return drivers.ParamValue(bc.provides.Params, name)
+}
+
+// requiredParam - the value of a provided param, which must be set
+func (bc *buildContext) requiredParam(name string) (string, error) {
+	return drivers.RequiredParam(bc.provides, name)
+}
+
+// indexProvided - the name of every storage.volume provided by the components (its 'name' param, or the name of the
+// resource), and every environment.secret and environment.properties resource
+func indexProvided(components map[string]*model.Component) *providedResources {
+	results := &providedResources{volumes: make(map[string]bool), environment: make(map[string]*model.Resource)}
+	for resID, curProvides := range drivers.ProvidedResources(components) {
+		switch curProvides.Type {
+		case "storage.volume":
+			results.volumes[drivers.ProvidedName(curProvides)] = true
+		case "environment.secret", "environment.properties":
+			results.environment[resID] = curProvides
+		}
+	}
+	return results
+}
+
+func buildWebApp(bc *buildContext, project *Project) error {
+	return buildContainer(bc, project, "port")
+}
+
+func buildPostgres(bc *buildContext, project *Project) error {
+	return buildContainer(bc, project, "db_port")
+}
+
+// buildContainer - a service running the resource's image, which publishes its port on the same port of the host
+func buildContainer(bc *buildContext, project *Project, portParam string) error {
+	if project.Services[bc.provides.Name] != nil {
+		return fmt.Errorf("%s: there's more than one service named '%s'", bc.resourceID(), bc.provides.Name)
+	}
+	image, err := bc.requiredParam("imageName")
+	if err != nil {
+		return err
+	}
+	port, err := bc.requiredParam(portParam)
+	if err != nil {
+		return err
+	}
+	parsedPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("%s: '%s' is not a valid number for '%s'", bc.resourceID(), port, portParam)
+	}
+	service := &Service{Image: image, Ports: []ServicePort{{Target: int(parsedPort), Published: int(parsedPort)}}}
+	if service.Environment, err = bc.environment(); err != nil {
+		return err
+	}
+	if service.Volumes, err = bc.volumeMounts(); err != nil {
+		return err
+	}
+	service.Healthcheck = bc.healthcheck(port, service.Environment)
+	project.Services[bc.provides.Name] = service
+	return nil
+}
+
+// healthchecks - the command which checks that a service is healthy, for the resource types where it's known. It's
+// given the service's port and environment.
+var healthchecks = map[string]func(port string, environment map[string]string) []string{
+	"service.db.postgres": postgresHealthcheck,
+}
+
+// The timing of generated healthchecks, unless the component's deployment hints say otherwise
+const (
+	DefaultHealthcheckInterval = 10
+	DefaultHealthcheckTimeout  = 5
+	DefaultHealthcheckRetries  = 5
+)
+
+// healthcheck - the healthcheck of a service, when its resource type has one. The interval, start period and retries
+// come from the component's healthCheck hint.
+func (bc *buildContext) healthcheck(port string, environment map[string]string) *Healthcheck {
+	test := healthchecks[bc.provides.Type]
+	if test == nil {
+		return nil
+	}
+	interval, retries, startPeriod := DefaultHealthcheckInterval, DefaultHealthcheckRetries, 0
+	if hints := bc.component.DeploymentHints; hints != nil && hints.HealthCheck != nil {
+		if hints.HealthCheck.PeriodSeconds > 0 {
+			interval = hints.HealthCheck.PeriodSeconds
+		}
+		if hints.HealthCheck.FailureThreshold > 0 {
+			retries = hints.HealthCheck.FailureThreshold
+		}
+		startPeriod = hints.HealthCheck.InitialDelaySeconds
+	}
+	results := &Healthcheck{Test: test(port, environment), Interval: duration(interval), Retries: retries,
+		Timeout: duration(DefaultHealthcheckTimeout)}
+	if startPeriod > 0 {
+		results.StartPeriod = duration(startPeriod)
+	}
+	return results
+}
+
+// postgresHealthcheck - pg_isready, as the database's user (POSTGRES_USER, or postgres by default). The environment's
+// values are already escaped for Compose.
+func postgresHealthcheck(port string, environment map[string]string) []string {
+	user := environment["POSTGRES_USER"]
+	if len(user) == 0 {
+		user = "postgres"
+	}
+	return []string{"CMD", "pg_isready", "-U", user, "-p", port}
+}
+
+func duration(seconds int) string {
+	return strconv.Itoa(seconds) + "s"
+}
+
+// environment - the environment variables of a service, from the resources it uses:
+//   - 'environment' and 'secret' - each param is an environment variable
+//   - 'environment.secret' / 'environment.properties' (named after the resource) - without params, every param of the
+//     resource is an environment variable. Otherwise, each param is an environment variable, and its value names the
+//     resource's param
+func (bc *buildContext) environment() (map[string]string, error) {
+	results := make(map[string]string)
+	for _, curUses := range drivers.SortedUses(bc.component) {
+		switch curUses.Type {
+		case "environment", "secret":
+			for _, curParam := range drivers.SortedParams(curUses.Params) {
+				results[curParam.Name] = curParam.Value
+			}
+		case "environment.secret", "environment.properties":
+			if bc.provided == nil {
+				continue
+			}
+			resource := bc.provided.environment[drivers.ResourceID(curUses)]
+			if resource == nil {
+				return nil, fmt.Errorf("%s: the %s used by %s isn't provided by any component (no %s is named '%s')", bc.resourceID(),
+					curUses.Type, bc.component.Name, curUses.Type, curUses.Name)
+			}
+			if len(curUses.Params) == 0 {
+				for _, curParam := range drivers.SortedParams(resource.Params) {
+					results[curParam.Name] = curParam.Value
+				}
+				continue
+			}
+			for _, curParam := range drivers.SortedParams(curUses.Params) {
+				key := curParam.Value
+				if len(key) == 0 {
+					key = curParam.Name
+				}
+				results[curParam.Name] = drivers.ParamValue(resource.Params, key)
+			}
+		}
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	for name, value := range results {
+		results[name] = escapeInterpolation(value)
+	}
+	return results, nil
+}
+
+// escapeInterpolation escapes the '$' of a value, so that Compose doesn't interpolate it (e.g. a password like pa$word)
+func escapeInterpolation(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+// volumeMounts - a mount for each 'storage' resource used by the component, sorted by their key. Each one names a
+// volume ('volumeName'), and where it's mounted ('mountPath', and optionally 'readOnly').
+func (bc *buildContext) volumeMounts() ([]string, error) {
+	var results []string
+	for _, curUses := range drivers.SortedUses(bc.component) {
+		if curUses.Type != "storage" {
+			continue
+		}
+		volume := drivers.ParamValue(curUses.Params, "volumeName")
+		if len(volume) == 0 {
+			return nil, fmt.Errorf("%s: the storage used by %s doesn't name a volume (set 'volumeName')", bc.resourceID(), bc.component.Name)
+		}
+		if bc.provided != nil && !bc.provided.volumes[volume] {
+			return nil, fmt.Errorf("%s: the volume '%s' isn't provided by any component (no storage.volume is named '%s')",
+				bc.resourceID(), volume, volume)
+		}
+		mountPath := drivers.ParamValue(curUses.Params, "mountPath")
+		if len(mountPath) == 0 {
+			return nil, fmt.Errorf("%s: the storage used by %s doesn't have a 'mountPath'", bc.resourceID(), bc.component.Name)
+		}
+		mount := volume + ":" + mountPath
+		if readOnly := drivers.ParamValue(curUses.Params, "readOnly"); len(readOnly) > 0 {
+			parsed, err := strconv.ParseBool(readOnly)
+			if err != nil {
+				return nil, fmt.Errorf("%s: '%s' is not a valid value for 'readOnly' (use true or false)", bc.resourceID(), readOnly)
+			}
+			if parsed {
+				mount += ":ro"
+			}
+		}
+		results = append(results, mount)
+	}
+	return results, nil
+}
+
+// buildVolume - a named volume, created by the container engine
+func buildVolume(bc *buildContext, project *Project) error {
+	project.Volumes[drivers.ProvidedName(bc.provides)] = &Volume{}
+	return nil
+}
+
+// buildVolumeClaim - claims are a Kubernetes concept; a service mounts the volume itself
+func buildVolumeClaim(bc *buildContext, project *Project) error {
+	return nil
+}
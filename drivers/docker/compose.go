@@ -0,0 +1,190 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The Docker Compose file (https://github.com/compose-spec/compose-spec/blob/master/spec.md), as generated by this
+// driver. The structures only hold the fields which rezolvr generates; fields from custom templates which aren't
+// listed are kept in Extra.
+
+// ComposeVersion - the version written to the compose file, for older versions of docker-compose
+const ComposeVersion = "3.8"
+
+// Project - a compose file. Maps are written sorted by name, so the output is stable.
+type Project struct {
+	Version  string              `yaml:"version,omitempty"`
+	Services map[string]*Service `yaml:"services,omitempty"`
+	Volumes  map[string]*Volume  `yaml:"volumes,omitempty"`
+	Networks map[string]*Network `yaml:"networks,omitempty"`
+	Secrets  map[string]*Secret  `yaml:"secrets,omitempty"`
+	Configs  map[string]*Config  `yaml:"configs,omitempty"`
+}
+
+// Service - a container, and how it's run
+type Service struct {
+	Image       string                       `yaml:"image,omitempty"`
+	Volumes     []string                     `yaml:"volumes,omitempty"`
+	Ports       []ServicePort                `yaml:"ports,omitempty"`
+	Environment ServiceEnvironment           `yaml:"environment,omitempty"`
+	Networks    []string                     `yaml:"networks,omitempty"`
+	Secrets     []string                     `yaml:"secrets,omitempty"`
+	Configs     []string                     `yaml:"configs,omitempty"`
+	DependsOn   map[string]ServiceDependency `yaml:"depends_on,omitempty"`
+	Healthcheck *Healthcheck                 `yaml:"healthcheck,omitempty"`
+	Extra       map[string]interface{}       `yaml:",inline"`
+}
+
+// ServiceDependency - when a service which another one depends on is considered ready: service_started, or
+// service_healthy once its healthcheck passes
+type ServiceDependency struct {
+	Condition string `yaml:"condition"`
+}
+
+// Healthcheck - how the container engine checks that a service is healthy
+type Healthcheck struct {
+	Test        HealthcheckTest `yaml:"test"`
+	Interval    string          `yaml:"interval,omitempty"`
+	Timeout     string          `yaml:"timeout,omitempty"`
+	Retries     int             `yaml:"retries,omitempty"`
+	StartPeriod string          `yaml:"start_period,omitempty"`
+}
+
+// HealthcheckTest - the command which checks a service, e.g. [CMD, pg_isready]. It's written as a list.
+type HealthcheckTest []string
+
+// UnmarshalYAML reads either syntax of a test (e.g. from a custom template). A string is run by the container's shell,
+// so it's the same as a list of CMD-SHELL and the string.
+func (ht *HealthcheckTest) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var command string
+	if err := unmarshal(&command); err != nil {
+		return unmarshal((*[]string)(ht))
+	}
+	*ht = HealthcheckTest{"CMD-SHELL", command}
+	return nil
+}
+
+// ServiceEnvironment - the environment variables of a service, by name. It's written as a map.
+type ServiceEnvironment map[string]string
+
+// UnmarshalYAML reads either syntax of an environment (e.g. from a custom template): a map, or a list of KEY=VALUE.
+// Variables without a value, which Compose reads from the shell, aren't supported.
+func (se *ServiceEnvironment) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return unmarshal((*map[string]string)(se))
+	}
+	*se = make(ServiceEnvironment, len(list))
+	for _, curVariable := range list {
+		equals := strings.Index(curVariable, "=")
+		if equals <= 0 {
+			return fmt.Errorf("'%s' is not a valid environment variable (use KEY=VALUE)", curVariable)
+		}
+		(*se)[curVariable[:equals]] = curVariable[equals+1:]
+	}
+	return nil
+}
+
+// ServicePort - a port of a container, which is published on a port of the host. The long syntax is used, as the short
+// one (e.g. 22:22) may be read as a number.
+type ServicePort struct {
+	Target    int    `yaml:"target"`
+	HostIP    string `yaml:"host_ip,omitempty"`
+	Published int    `yaml:"published,omitempty"`
+	Protocol  string `yaml:"protocol,omitempty"`
+}
+
+// servicePortFields - the long syntax of a port, without UnmarshalYAML
+type servicePortFields ServicePort
+
+// UnmarshalYAML reads either syntax of a port (e.g. from a custom template). The short syntax is a container port,
+// optionally preceded by the host's port (and IP address), and followed by the protocol, e.g. 127.0.0.1:8080:80/tcp.
+func (sp *ServicePort) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var short string
+	if err := unmarshal(&short); err != nil {
+		return unmarshal((*servicePortFields)(sp))
+	}
+	*sp = ServicePort{}
+	if slash := strings.LastIndex(short, "/"); slash >= 0 {
+		sp.Protocol = short[slash+1:]
+		short = short[:slash]
+	}
+	parts := strings.Split(short, ":")
+	if len(parts) > 3 {
+		return fmt.Errorf("'%s' is not a valid port", short)
+	}
+	ports := make([]int, 0, 2)
+	for i := len(parts) - 1; i >= 0 && len(ports) < 2; i-- {
+		port, err := strconv.ParseUint(parts[i], 10, 16)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid port (port ranges aren't supported)", short)
+		}
+		ports = append(ports, int(port))
+	}
+	sp.Target = ports[0]
+	if len(ports) > 1 {
+		sp.Published = ports[1]
+	}
+	if len(parts) == 3 {
+		sp.HostIP = parts[0]
+	}
+	return nil
+}
+
+// Volume - a named volume. A volume without any fields is created by the container engine's default driver.
+type Volume struct {
+	Name       string                 `yaml:"name,omitempty"`
+	Driver     string                 `yaml:"driver,omitempty"`
+	DriverOpts map[string]string      `yaml:"driver_opts,omitempty"`
+	External   bool                   `yaml:"external,omitempty"`
+	Labels     map[string]string      `yaml:"labels,omitempty"`
+	Extra      map[string]interface{} `yaml:",inline"`
+}
+
+// Network - a network which services are attached to
+type Network struct {
+	Name     string                 `yaml:"name,omitempty"`
+	Driver   string                 `yaml:"driver,omitempty"`
+	Internal bool                   `yaml:"internal,omitempty"`
+	External bool                   `yaml:"external,omitempty"`
+	Labels   map[string]string      `yaml:"labels,omitempty"`
+	Extra    map[string]interface{} `yaml:",inline"`
+}
+
+// Secret - sensitive data, which is mounted into the services which use it
+type Secret struct {
+	File        string `yaml:"file,omitempty"`
+	Environment string `yaml:"environment,omitempty"`
+	External    bool   `yaml:"external,omitempty"`
+	Name        string `yaml:"name,omitempty"`
+}
+
+// Config - non-sensitive data, which is mounted into the services which use it
+type Config struct {
+	File        string `yaml:"file,omitempty"`
+	Environment string `yaml:"environment,omitempty"`
+	Content     string `yaml:"content,omitempty"`
+	External    bool   `yaml:"external,omitempty"`
+	Name        string `yaml:"name,omitempty"`
+}
+
+func newProject() *Project {
+	return &Project{Version: ComposeVersion, Services: make(map[string]*Service), Volumes: make(map[string]*Volume),
+		Networks: make(map[string]*Network), Secrets: make(map[string]*Secret), Configs: make(map[string]*Config)}
+}
@@ -0,0 +1,120 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"rezolvr/drivers"
+	"rezolvr/model"
+	"sort"
+	"strings"
+)
+
+// The conditions of depends_on
+const (
+	ConditionStarted = "service_started"
+	ConditionHealthy = "service_healthy"
+)
+
+// NetworkSuffix - the suffix of the network on which a service is reached by the services which depend on it
+const NetworkSuffix = "-net"
+
+// DefaultNetwork - the network which Compose creates for the project. Compose attaches every service which doesn't list
+// any networks to it, which would let each service reach every other one. Instead, the services of the dependency graph
+// only join the networks of the services they depend on (and their own), so that a service is only reachable by those
+// which depend on it. Only the services without any dependencies list the default network.
+const DefaultNetwork = "default"
+
+// componentServices - the services generated for the resources a component provides, by resource ID (e.g.
+// service.db.postgres:mydb)
+func componentServices(c *model.Component, platformSettings map[string]*model.Platform, project *Project) map[string]string {
+	results := make(map[string]string)
+	for _, curProvides := range c.Provides {
+		if !strings.HasPrefix(curProvides.Type, "service") || project.Services[curProvides.Name] == nil ||
+			drivers.SkipOutput(curProvides, platformSettings, logger) {
+			continue
+		}
+		results[drivers.ResourceID(curProvides)] = curProvides.Name
+	}
+	return results
+}
+
+// linkDependencies adds the dependency graph to the compose file. When a component needs a resource which another
+// component provides as a service, each of its services depends on that service (once it's healthy, when it has a
+// healthcheck). The service which is needed is attached to a network named after it, and so is each service which
+// depends on it. The other services stay on the default network (see DefaultNetwork).
+func linkDependencies(components map[string]*model.Component, platformSettings map[string]*model.Platform, project *Project) {
+	providers := make(map[string]string)
+	services := make(map[string][]string)
+	for k, curComponent := range components {
+		for resourceID, name := range componentServices(curComponent, platformSettings, project) {
+			providers[resourceID] = name
+			services[k] = append(services[k], name)
+		}
+	}
+	networks := make(map[string]map[string]bool)
+	join := func(service string, network string) {
+		if networks[service] == nil {
+			networks[service] = make(map[string]bool)
+		}
+		networks[service][network] = true
+	}
+	for k, curComponent := range components {
+		for _, curNeeds := range curComponent.Needs {
+			provider, ok := providers[drivers.ResourceID(curNeeds)]
+			if !ok {
+				continue
+			}
+			condition := ConditionStarted
+			if project.Services[provider].Healthcheck != nil {
+				condition = ConditionHealthy
+			}
+			network := provider + NetworkSuffix
+			for _, curService := range services[k] {
+				if curService == provider {
+					continue
+				}
+				service := project.Services[curService]
+				if service.DependsOn == nil {
+					service.DependsOn = make(map[string]ServiceDependency)
+				}
+				service.DependsOn[provider] = ServiceDependency{Condition: condition}
+				join(curService, network)
+				join(provider, network)
+			}
+		}
+	}
+	for curService, curNetworks := range networks {
+		service := project.Services[curService]
+		for _, existing := range service.Networks {
+			curNetworks[existing] = true
+		}
+		service.Networks = make([]string, 0, len(curNetworks))
+		for network := range curNetworks {
+			service.Networks = append(service.Networks, network)
+			if project.Networks[network] == nil {
+				project.Networks[network] = &Network{}
+			}
+		}
+		sort.Strings(service.Networks)
+	}
+	if len(networks) == 0 {
+		return
+	}
+	for name, curService := range project.Services {
+		if networks[name] == nil && len(curService.Networks) == 0 {
+			curService.Networks = []string{DefaultNetwork}
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package docker generates Docker Compose files from resolved components
+package docker
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"rezolvr/drivers"
+	"rezolvr/model"
+	"rezolvr/templates"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Driver is the Docker Compose driver. It implements model.RezolvrDriver.
+type Driver struct{}
+
+// DriverName is the name used to select this driver, in an environment file's 'driver' field
+const DriverName = "docker"
+
+// DriverVersion is the version of this driver
+const DriverVersion = "0.0.1"
+
+// FormatCompose - the output format of this driver
+const FormatCompose = "docker-compose"
+
+// templateFiles holds the default templates, which are compiled into the driver
+//
+//go:embed templates/*.template
+var templateFiles embed.FS
+
+// builtInTemplates - the default templates, used when neither the project nor the user overrides them
+var builtInTemplates = drivers.MustSub(templateFiles, "templates")
+
+func init() {
+	drivers.Register(DriverName, func() model.RezolvrDriver {
+		return Driver{}
+	})
+	drivers.RegisterTemplates(DriverName, builtInTemplates)
+}
+
+// logger is supplied by rezolvr, via SetLogger
+var logger = model.GetLogger()
+
+// Name - the name used to select this driver
+func (rd Driver) Name() string {
+	return DriverName
+}
+
+// Version - the version of this driver
+func (rd Driver) Version() string {
+	return DriverVersion
+}
+
+// SetLogger - use rezolvr's logger, so that messages honour its log level and format
+func (rd Driver) SetLogger(newLogger model.Logger) {
+	logger = newLogger
+}
+
+// Capabilities - the resource types which are generated from the typed model, or have a built-in template, and the
+// output formats of this driver
+func (rd Driver) Capabilities() model.Capabilities {
+	capabilities := model.Capabilities{APIVersion: model.DriverAPIVersion, OutputFormats: []string{FormatCompose}}
+	capabilities.ResourceTypes = builderTypes()
+	builtIn, _ := fs.Glob(builtInTemplates, "*"+templates.Extension)
+	for _, curFileName := range builtIn {
+		name := strings.TrimSuffix(curFileName, templates.Extension)
+		if builders[name] == nil {
+			capabilities.ResourceTypes = append(capabilities.ResourceTypes, name)
+		}
+	}
+	sort.Strings(capabilities.ResourceTypes)
+	return capabilities
+}
+
+// Validate - check that the compose file can be generated (including that each volume a service mounts, and each
+// environment resource it uses, is provided by a component), and that every template it needs can be parsed
+func (rd Driver) Validate(ctx context.Context, request *model.TransformRequest) error {
+	_, err := rd.buildProject(request)
+	return err
+}
+
+type providesTemplate struct {
+	Type     string
+	contents string
+}
+
+func (rd Driver) loadTemplate(baseDir string, templateName string) (providesTemplate, error) {
+	templateType := strings.Split(templateName, ".")[0]
+	loaded, err := templates.NewLoader(DriverName, baseDir, builtInTemplates).Load(templateName)
+	if err != nil {
+		return providesTemplate{}, err
+	}
+	logger.Tracef("Using the %s template for %s: %s", loaded.Source, templateName, loaded.Location)
+	return providesTemplate{Type: templateType, contents: string(loaded.Content)}, nil
+}
+
+func (rd Driver) populateTemplate(templateSource string, curProvides *model.Resource, r *model.Component) (string, error) {
+
+	// The following two variables are made available to the eval() method
+	data := map[string]interface{}{
+		"Provides":      curProvides,
+		"ProvideParams": curProvides.Params,
+		"Uses":          r.Uses,
+		"Res":           r,
+	}
+
+	resourceID := drivers.ResourceID(curProvides)
+	t, err := template.New(curProvides.Type).Parse(templateSource)
+	if err != nil {
+		return "", fmt.Errorf("invalid Docker Compose template for %s: %v", resourceID, err)
+	}
+	buf := &bytes.Buffer{}
+	err = t.Execute(buf, data)
+	if err != nil {
+		return "", fmt.Errorf("error resolving the Docker Compose template for %s: %v", resourceID, err)
+	}
+	return buf.String(), nil
+}
+
+// addTemplate adds the output of a template to the compose file. The template holds the body of a service (for
+// 'service' types) or a volume (for 'storage' types, named by its 'name' param), indented by four spaces; other types
+// have no output.
+func (rd Driver) addTemplate(curTemplate providesTemplate, curProvides *model.Resource, r *model.Component, project *Project) error {
+	filledInTemplate, err := rd.populateTemplate(curTemplate.contents, curProvides, r)
+	if err != nil {
+		return err
+	}
+	resourceID := drivers.ResourceID(curProvides)
+	switch curTemplate.Type {
+	case "service":
+		services := map[string]*Service{}
+		if err := yaml.Unmarshal([]byte("  "+curProvides.Name+":\n"+filledInTemplate), &services); err != nil {
+			return fmt.Errorf("the Docker Compose template for %s isn't valid YAML: %v", resourceID, err)
+		}
+		if project.Services[curProvides.Name] != nil {
+			return fmt.Errorf("%s: there's more than one service named '%s'", resourceID, curProvides.Name)
+		}
+		project.Services[curProvides.Name] = services[curProvides.Name]
+		if project.Services[curProvides.Name] == nil {
+			project.Services[curProvides.Name] = &Service{}
+		}
+	case "storage":
+		// Named like the volumes of the builder, so that the services' mounts refer to it
+		name := drivers.ProvidedName(curProvides)
+		volumes := map[string]*Volume{}
+		if err := yaml.Unmarshal([]byte("  "+name+":\n"+filledInTemplate), &volumes); err != nil {
+			return fmt.Errorf("the Docker Compose template for %s isn't valid YAML: %v", resourceID, err)
+		}
+		project.Volumes[name] = volumes[name]
+		if project.Volumes[name] == nil {
+			project.Volumes[name] = &Volume{}
+		}
+	}
+	return nil
+}
+
+// transformProvidedResource adds each resource provided by a component to the compose file. A template named after the
+// resource type replaces the typed model; resource types without a builder always use a template.
+func (rd Driver) transformProvidedResource(r *model.Component, pluginDir string, provided *providedResources, platformSettings map[string]*model.Platform,
+	project *Project) error {
+	for _, curProvides := range drivers.SortedProvides(r) {
+		if drivers.SkipOutput(curProvides, platformSettings, logger) {
+			continue
+		}
+		template, err := rd.loadTemplate(pluginDir, curProvides.Type)
+		build := builders[curProvides.Type]
+		if err != nil && build != nil {
+			if err := build(&buildContext{component: r, provides: curProvides, provided: provided}, project); err != nil {
+				return err
+			}
+		} else if err != nil {
+			logger.Warnf("%v. Output will be skipped for: %v", err, curProvides.Type)
+		} else if len(template.contents) < 5 {
+			logger.Debugf("Empty template found. Output will be skipped for: %v", curProvides.Type)
+		} else if err := rd.addTemplate(template, curProvides, r, project); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildProject - the compose file of every component in the state, along with the updated components, and the
+// dependencies between their services. Components are added in the order of their IDs, so that errors are reported
+// consistently.
+func (rd Driver) buildProject(request *model.TransformRequest) (*Project, error) {
+	project := newProject()
+	components := drivers.AllComponents(request)
+	provided := indexProvided(components)
+	componentIDs := make([]string, 0, len(components))
+	for k := range components {
+		componentIDs = append(componentIDs, k)
+	}
+	sort.Strings(componentIDs)
+	for _, k := range componentIDs {
+		if err := rd.transformProvidedResource(components[k], request.PluginDir, provided, request.PlatformSettings, project); err != nil {
+			return nil, err
+		}
+	}
+	linkDependencies(components, request.PlatformSettings, project)
+	return project, nil
+}
+
+// Transform generates the compose file for every component in the state, along with the updated components
+func (rd Driver) Transform(ctx context.Context, request *model.TransformRequest) (*model.TransformResult, error) {
+	result := &model.TransformResult{Artifacts: []model.Artifact{}}
+	if request.UpdatedComponents == nil {
+		logger.Infof("No components / resources to transform")
+		return result, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// This transformer regenerates all components within the state. However,
+	// the updated components should take precedence, obviously.
+	project, err := rd.buildProject(request)
+	if err != nil {
+		return nil, err
+	}
+	// Return the compose file to rezolvr, which writes it
+	if len(project.Services) > 0 || len(project.Volumes) > 0 {
+		content, err := yaml.Marshal(project)
+		if err != nil {
+			return nil, err
+		}
+		result.Artifacts = append(result.Artifacts, model.Artifact{Path: "docker-compose.yaml", Format: FormatCompose, Content: content})
+	} else {
+		logger.Infof("No services or volumes were generated. Skipping the generation of a compose file...")
+	}
+	return result, nil
+}
@@ -0,0 +1,298 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rezolvr/drivers/drivertest"
+	"rezolvr/model"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v2"
+)
+
+func testRequest() *model.TransformRequest {
+	return &model.TransformRequest{
+		UpdatedComponents: map[string]*model.Component{
+			"resource.web.app:catalog": {Name: "catalog", Type: "resource.web.app",
+				Provides: map[string]*model.Resource{
+					"service.web.app:catalogapp": {Name: "catalogapp", Type: "service.web.app", Params: drivertest.Params("imageName", "catalog", "port", "3001")},
+				},
+				Uses: map[string]*model.Resource{
+					"environment":                  {Type: "environment", Params: drivertest.Params("DB_PORT", "5432", "DB_PW", "p@ss: #word", "DB_USER", "pa$word")},
+					"environment.properties:flags": {Name: "flags", Type: "environment.properties"},
+				},
+				Needs: map[string]*model.Resource{
+					"service.db.postgres:mydb": {Name: "mydb", Type: "service.db.postgres"},
+				},
+			},
+			"resource.db.postgres:postgres": {Name: "postgres", Type: "resource.db.postgres",
+				Provides: map[string]*model.Resource{
+					"service.db.postgres:mydb": {Name: "mydb", Type: "service.db.postgres", Params: drivertest.Params("imageName", "postgres", "db_port", "5432")},
+				},
+				Uses: map[string]*model.Resource{
+					"environment.secret:creds": {Name: "creds", Type: "environment.secret", Params: drivertest.Params("POSTGRES_PASSWORD", "db_password")},
+					"storage":                  {Type: "storage", Params: drivertest.Params("volumeName", "dbvolume", "mountPath", "/var/lib/postgresql/data")},
+				},
+			},
+		},
+		State: &model.State{Components: map[string]*model.Component{
+			"resource.storage.volume:volumes": {Name: "volumes", Type: "resource.storage.volume", Provides: map[string]*model.Resource{
+				"storage.volume:dbvolume":            {Name: "dbvolume", Type: "storage.volume", Params: drivertest.Params("name", "dbvolume")},
+				"storage.volume-claim:dbvolumeclaim": {Name: "dbvolumeclaim", Type: "storage.volume-claim", Params: drivertest.Params("name", "dbvolumeclaim")},
+			}},
+			"environment.properties": {Name: "environment", Provides: map[string]*model.Resource{
+				"environment.properties:flags": {Name: "flags", Type: "environment.properties", Params: drivertest.Params("DEBUG", "yes")},
+				"environment.secret:creds":     {Name: "creds", Type: "environment.secret", Params: drivertest.Params("db_password", "s3cret")},
+			}},
+		}},
+	}
+}
+
+func Test_Transform(t *testing.T) {
+	result, err := Driver{}.Transform(context.Background(), testRequest())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result.Artifacts))
+	assert.Equal(t, "docker-compose.yaml", result.Artifacts[0].Path)
+	assert.Equal(t, `version: "3.8"
+services:
+  catalogapp:
+    image: catalog
+    ports:
+    - target: 3001
+      published: 3001
+    environment:
+      DB_PORT: "5432"
+      DB_PW: 'p@ss: #word'
+      DB_USER: pa$$word
+      DEBUG: "yes"
+    networks:
+    - mydb-net
+    depends_on:
+      mydb:
+        condition: service_healthy
+  mydb:
+    image: postgres
+    volumes:
+    - dbvolume:/var/lib/postgresql/data
+    ports:
+    - target: 5432
+      published: 5432
+    environment:
+      POSTGRES_PASSWORD: s3cret
+    networks:
+    - mydb-net
+    healthcheck:
+      test:
+      - CMD
+      - pg_isready
+      - -U
+      - postgres
+      - -p
+      - "5432"
+      interval: 10s
+      timeout: 5s
+      retries: 5
+volumes:
+  dbvolume: {}
+networks:
+  mydb-net: {}
+`, string(result.Artifacts[0].Content))
+	checkComposeSpec(t, result.Artifacts[0].Content)
+
+	// Values are read back exactly as they were resolved, once Compose has replaced each '$$' with '$'
+	project := &Project{}
+	assert.Nil(t, yaml.Unmarshal(result.Artifacts[0].Content, project))
+	assert.Equal(t, "p@ss: #word", project.Services["catalogapp"].Environment["DB_PW"])
+	assert.Equal(t, "pa$word", strings.ReplaceAll(project.Services["catalogapp"].Environment["DB_USER"], "$$", "$"))
+	assert.Equal(t, "yes", project.Services["catalogapp"].Environment["DEBUG"])
+}
+
+func Test_BuildErrors(t *testing.T) {
+	request := testRequest()
+	delete(request.State.Components, "resource.storage.volume:volumes")
+	err := Driver{}.Validate(context.Background(), request)
+	assert.EqualError(t, err, "service.db.postgres:mydb: the volume 'dbvolume' isn't provided by any component (no storage.volume is named 'dbvolume')")
+
+	request = testRequest()
+	request.UpdatedComponents["resource.web.app:catalog"].Provides["service.web.app:catalogapp"].Params["port"].Value = "http"
+	err = Driver{}.Validate(context.Background(), request)
+	assert.EqualError(t, err, "service.web.app:catalogapp: 'http' is not a valid number for 'port'")
+}
+
+func Test_Dependencies(t *testing.T) {
+	// A web app which depends on another is started once the other has started, as it has no healthcheck
+	request := testRequest()
+	request.UpdatedComponents["resource.web.app:frontend"] = &model.Component{Name: "frontend", Type: "resource.web.app",
+		Provides: map[string]*model.Resource{
+			"service.web.app:frontendapp": {Name: "frontendapp", Type: "service.web.app", Params: drivertest.Params("imageName", "frontend", "port", "8080")},
+		},
+		Needs: map[string]*model.Resource{
+			"service.web.app:catalogapp": {Name: "catalogapp", Type: "service.web.app"},
+			"storage.volume:dbvolume":    {Name: "dbvolume", Type: "storage.volume"},
+		},
+	}
+	// A web app outside the dependency graph stays on the default network
+	request.UpdatedComponents["resource.web.app:admin"] = &model.Component{Name: "admin", Type: "resource.web.app",
+		Provides: map[string]*model.Resource{
+			"service.web.app:adminapp": {Name: "adminapp", Type: "service.web.app", Params: drivertest.Params("imageName", "admin", "port", "9090")},
+		},
+	}
+	// The healthcheck's timing follows the deployment hints, and the database's user
+	request.UpdatedComponents["resource.db.postgres:postgres"].DeploymentHints = &model.DeploymentHints{
+		HealthCheck: &model.HealthCheck{InitialDelaySeconds: 30, PeriodSeconds: 20, FailureThreshold: 3}}
+	request.UpdatedComponents["resource.db.postgres:postgres"].Uses["environment"] = &model.Resource{Type: "environment",
+		Params: drivertest.Params("POSTGRES_USER", "$admin")}
+	project, err := Driver{}.buildProject(request)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]ServiceDependency{"catalogapp": {Condition: ConditionStarted}}, project.Services["frontendapp"].DependsOn)
+	// Each service is only reachable by those which depend on it
+	assert.Equal(t, []string{"catalogapp-net"}, project.Services["frontendapp"].Networks)
+	assert.Equal(t, []string{"catalogapp-net", "mydb-net"}, project.Services["catalogapp"].Networks)
+	assert.Equal(t, []string{"mydb-net"}, project.Services["mydb"].Networks)
+	assert.Equal(t, []string{DefaultNetwork}, project.Services["adminapp"].Networks)
+	assert.Equal(t, map[string]*Network{"catalogapp-net": {}, "mydb-net": {}}, project.Networks)
+	assert.Equal(t, &Healthcheck{Test: []string{"CMD", "pg_isready", "-U", "$$admin", "-p", "5432"}, Interval: "20s", Timeout: "5s",
+		Retries: 3, StartPeriod: "30s"}, project.Services["mydb"].Healthcheck)
+
+	// An external database isn't part of the compose file, so nothing depends on it
+	request = testRequest()
+	request.PlatformSettings = map[string]*model.Platform{"mydb": {Params: drivertest.Params("isExternal", "true")}}
+	project, err = Driver{}.buildProject(request)
+	assert.Nil(t, err)
+	assert.Nil(t, project.Services["catalogapp"].DependsOn)
+	assert.Nil(t, project.Services["catalogapp"].Networks)
+	assert.Empty(t, project.Networks)
+}
+
+func Test_CustomTemplates(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "templates"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "templates", "service.web.app.template"), []byte(`    image: {{.ProvideParams.imageName.Value}}
+    ports:
+      - "127.0.0.1:8080:{{.ProvideParams.port.Value}}/tcp"
+      - 9000
+    restart: always
+`), 0644))
+	request := testRequest()
+	request.PluginDir = dir
+	result, err := Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	project := &Project{}
+	assert.Nil(t, yaml.Unmarshal(result.Artifacts[0].Content, project))
+	catalog := project.Services["catalogapp"]
+	assert.Equal(t, []ServicePort{{Target: 3001, HostIP: "127.0.0.1", Published: 8080, Protocol: "tcp"}, {Target: 9000}}, catalog.Ports)
+	assert.Equal(t, map[string]interface{}{"restart": "always"}, catalog.Extra)
+	checkComposeSpec(t, result.Artifacts[0].Content)
+
+	// The list syntax of the environment, and the string syntax of a healthcheck's test, are read as well
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "templates", "service.web.app.template"), []byte(`    image: {{.ProvideParams.imageName.Value}}
+    environment:
+      - DEBUG=yes
+      - DB_URL=postgres://mydb:5432/catalog?sslmode=disable
+    healthcheck:
+      test: curl -f http://localhost:{{.ProvideParams.port.Value}}/health
+`), 0644))
+	result, err = Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	project = &Project{}
+	assert.Nil(t, yaml.Unmarshal(result.Artifacts[0].Content, project))
+	catalog = project.Services["catalogapp"]
+	assert.Equal(t, ServiceEnvironment{"DEBUG": "yes", "DB_URL": "postgres://mydb:5432/catalog?sslmode=disable"}, catalog.Environment)
+	assert.Equal(t, HealthcheckTest{"CMD-SHELL", "curl -f http://localhost:3001/health"}, catalog.Healthcheck.Test)
+	checkComposeSpec(t, result.Artifacts[0].Content)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "templates", "service.web.app.template"), []byte("    environment:\n      - DEBUG\n"), 0644))
+	_, err = Driver{}.Transform(context.Background(), request)
+	assert.EqualError(t, err, "the Docker Compose template for service.web.app:catalogapp isn't valid YAML: 'DEBUG' is not a valid environment variable (use KEY=VALUE)")
+	assert.Nil(t, os.Remove(filepath.Join(dir, "templates", "service.web.app.template")))
+
+	// A volume's template is named like the generated volume, after its 'name' param, so that mounts still refer to it
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "templates", "storage.volume.template"), []byte("    driver: local\n"), 0644))
+	request.State.Components["resource.storage.volume:volumes"].Provides["storage.volume:dbvolume"].Params = drivertest.Params("name", "pgdata")
+	request.UpdatedComponents["resource.db.postgres:postgres"].Uses["storage"].Params["volumeName"].Value = "pgdata"
+	project, err = Driver{}.buildProject(request)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]*Volume{"pgdata": {Driver: "local"}}, project.Volumes)
+	assert.Equal(t, []string{"pgdata:/var/lib/postgresql/data"}, project.Services["mydb"].Volumes)
+}
+
+func Test_Capabilities(t *testing.T) {
+	assert.Equal(t, []string{"environment.properties", "environment.secret", "service.container.registry", "service.db.postgres",
+		"service.web.app", "storage.volume", "storage.volume-claim"}, Driver{}.Capabilities().ResourceTypes)
+}
+
+// composeSpecFile - the Compose specification's schema, from github.com/compose-spec/compose-go v1.20.2
+var composeSpecFile = filepath.Join("testdata", "compose-spec.json")
+
+// checkComposeSpec validates a compose file against the Compose specification's schema
+func checkComposeSpec(t *testing.T, content []byte) {
+	result, err := validateComposeSpec(content)
+	if !assert.Nil(t, err) {
+		return
+	}
+	for _, curError := range result.Errors() {
+		assert.Fail(t, "The compose file doesn't follow the Compose specification", curError.String())
+	}
+}
+
+func validateComposeSpec(content []byte) (*gojsonschema.Result, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	schema, err := filepath.Abs(composeSpecFile)
+	if err != nil {
+		return nil, err
+	}
+	return gojsonschema.Validate(gojsonschema.NewReferenceLoader("file://"+filepath.ToSlash(schema)), gojsonschema.NewGoLoader(jsonValue(doc)))
+}
+
+// jsonValue converts the maps decoded from YAML, which may have any type of key, into those of JSON
+func jsonValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[interface{}]interface{}:
+		results := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			results[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return results
+	case []interface{}:
+		results := make([]interface{}, len(typed))
+		for i, v := range typed {
+			results[i] = jsonValue(v)
+		}
+		return results
+	}
+	return value
+}
+
+func Test_ComposeSpec(t *testing.T) {
+	// The schema rejects what Compose would, e.g. unknown properties and mistyped values
+	result, err := validateComposeSpec([]byte("services:\n  app:\n    image: app\n    replica: 2\n    ports:\n    - target: http\n"))
+	assert.Nil(t, err)
+	assert.False(t, result.Valid())
+	messages := make([]string, 0, len(result.Errors()))
+	for _, curError := range result.Errors() {
+		messages = append(messages, curError.String())
+	}
+	assert.Contains(t, messages, "services.app: Additional property replica is not allowed")
+	assert.Contains(t, messages, "services.app.ports.0.target: Invalid type. Expected: integer, given: string")
+}
@@ -0,0 +1,29 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drivertest holds the fixtures shared by the tests of the drivers
+package drivertest
+
+import (
+	"rezolvr/model"
+)
+
+// Params - params with the given names and values, e.g. Params("imageName", "postgres", "db_port", "5432")
+func Params(nameValues ...string) map[string]*model.Param {
+	results := make(map[string]*model.Param)
+	for i := 0; i+1 < len(nameValues); i += 2 {
+		results[nameValues[i]] = &model.Param{Name: nameValues[i], Value: nameValues[i+1]}
+	}
+	return results
+}
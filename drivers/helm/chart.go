@@ -0,0 +1,297 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"rezolvr/drivers"
+	"rezolvr/drivers/kube"
+	"rezolvr/model"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The sections of values.yaml
+const (
+	// ValuesEnvironment - the params of each environment.properties resource (a ConfigMap)
+	ValuesEnvironment = "environment"
+	// ValuesSecrets - the params of each environment.secret resource (a Secret). They have no default, so they must be
+	// set when the chart is installed.
+	ValuesSecrets = "secrets"
+	// ValuesPlatform - the platform settings of each workload
+	ValuesPlatform = "platform"
+	// ValuesEnv - the env values of each workload which aren't read from a ConfigMap or Secret
+	ValuesEnv = "env"
+)
+
+// ChartMetadata - the contents of Chart.yaml
+type ChartMetadata struct {
+	APIVersion  string `yaml:"apiVersion"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Type        string `yaml:"type"`
+	Version     string `yaml:"version"`
+	AppVersion  string `yaml:"appVersion,omitempty"`
+}
+
+// chart - a chart which is being generated. Each value which is moved into values.yaml is replaced by a placeholder
+// within the objects, and the placeholder by a template expression once the objects are written.
+type chart struct {
+	metadata ChartMetadata
+	// values - the values, by section, resource name and key
+	values map[string]map[string]map[string]interface{}
+	// expressions - the template expression of each placeholder
+	expressions map[string]string
+	// secretValues - the values of secret params, which are never written to the chart
+	secretValues map[string]bool
+}
+
+// newChart - a chart named by the 'chartName' setting, or after the environment
+func newChart(request *model.TransformRequest) (*chart, error) {
+	var settings map[string]*model.Param
+	if platform := request.PlatformSettings["default"]; platform != nil {
+		settings = platform.Params
+	}
+	metadata := ChartMetadata{APIVersion: "v2", Type: "application", Name: drivers.ParamValue(settings, SettingChartName),
+		Version: drivers.ParamValue(settings, SettingChartVersion), AppVersion: drivers.ParamValue(settings, SettingAppVersion)}
+	if len(metadata.Name) == 0 {
+		metadata.Name = strings.Trim(invalidChartChars.ReplaceAllString(strings.ToLower(request.Environment), "-"), "-")
+		if len(metadata.Name) == 0 {
+			metadata.Name = DefaultChartName
+		}
+	} else if !chartName.MatchString(metadata.Name) {
+		return nil, fmt.Errorf("'%s' is not a valid chart name (use lower case letters, digits and '-')", metadata.Name)
+	}
+	if len(metadata.Version) == 0 {
+		metadata.Version = DefaultChartVersion
+	}
+	metadata.Description = "Generated by rezolvr"
+	if len(request.Environment) > 0 {
+		metadata.Description += " for the " + request.Environment + " environment"
+	}
+	secretValues := make(map[string]bool)
+	if request.State != nil {
+		secretValues = model.SecretValues(request.State.Components)
+	}
+	for curValue := range model.SecretValues(request.UpdatedComponents) {
+		secretValues[curValue] = true
+	}
+	return &chart{metadata: metadata, values: make(map[string]map[string]map[string]interface{}), expressions: make(map[string]string),
+		secretValues: secretValues}, nil
+}
+
+// placeholder records a value, and returns the placeholder of its template expression. String values are quoted.
+// Secret values aren't recorded: they're required when the chart is installed instead.
+func (c *chart) placeholder(section string, resourceName string, key string, value interface{}) string {
+	if section == ValuesSecrets {
+		return c.requiredPlaceholder(section, resourceName, key, true)
+	}
+	if curValue, ok := value.(string); ok && c.secretValues[curValue] {
+		return c.requiredPlaceholder(section, resourceName, key, false)
+	}
+	c.setValue(section, resourceName, key, value)
+	expression := fmt.Sprintf("(index .Values.%s %q %q)", section, resourceName, key)
+	if isString(value) {
+		return c.expression(fmt.Sprintf("{{ %s | quote }}", expression))
+	}
+	return c.expression(fmt.Sprintf("{{ %s }}", expression))
+}
+
+// requiredPlaceholder records an empty value, and returns the placeholder of an expression which fails when it isn't
+// set. Values of Secrets are base64 encoded.
+func (c *chart) requiredPlaceholder(section string, resourceName string, key string, encode bool) string {
+	c.setValue(section, resourceName, key, "")
+	expression := fmt.Sprintf("(index .Values.%s %q %q)", section, resourceName, key)
+	message := fmt.Sprintf("%s.%s.%s is required", section, resourceName, key)
+	if encode {
+		return c.expression(fmt.Sprintf("{{ required %q %s | b64enc | quote }}", message, expression))
+	}
+	return c.expression(fmt.Sprintf("{{ required %q %s | quote }}", message, expression))
+}
+
+func (c *chart) setValue(section string, resourceName string, key string, value interface{}) {
+	if c.values[section] == nil {
+		c.values[section] = make(map[string]map[string]interface{})
+	}
+	if c.values[section][resourceName] == nil {
+		c.values[section][resourceName] = make(map[string]interface{})
+	}
+	c.values[section][resourceName][key] = value
+}
+
+// expression - a new placeholder for a template expression
+func (c *chart) expression(expression string) string {
+	placeholder := fmt.Sprintf("__rezolvr_helm_value_%d__", len(c.expressions))
+	c.expressions[placeholder] = expression
+	return placeholder
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+// template converts a file generated by the Kubernetes driver into a template. The values of ConfigMaps and Secrets,
+// and the replicas, image pull policy and service type of workloads, are moved into values.yaml. Files which aren't
+// valid YAML (e.g. from a custom template) are used as-is.
+func (c *chart) template(resourceName string, manifest []byte) ([]byte, error) {
+	docs, err := kube.DecodeDocuments(manifest)
+	if err != nil {
+		logger.Debugf("%s isn't valid YAML, so it's used as-is in the chart: %v", resourceName, err)
+		return manifest, nil
+	}
+	buf := &bytes.Buffer{}
+	for i, curDoc := range docs {
+		c.parameterize(resourceName, curDoc)
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		out, err := yaml.Marshal(curDoc)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(out)
+	}
+	content := buf.String()
+	for placeholder, expression := range c.expressions {
+		content = strings.ReplaceAll(content, placeholder, expression)
+	}
+	return []byte(content), nil
+}
+
+// parameterize replaces the values of an object which may differ between installations with placeholders
+func (c *chart) parameterize(resourceName string, doc yaml.MapSlice) {
+	kind, _ := kube.Lookup(doc, "kind").(string)
+	switch kind {
+	case "ConfigMap", "Secret":
+		section := ValuesEnvironment
+		if kind == "Secret" {
+			section = ValuesSecrets
+		}
+		data, _ := kube.Lookup(doc, "data").(yaml.MapSlice)
+		for i, curItem := range data {
+			key := fmt.Sprint(curItem.Key)
+			if section == ValuesEnvironment && model.IsSecretParam("environment.properties"+model.IDSeparator+resourceName, key) {
+				// A secret which was declared as a property still isn't kept in the chart
+				data[i].Value = c.requiredPlaceholder(section, resourceName, key, false)
+				continue
+			}
+			data[i].Value = c.placeholder(section, resourceName, key, curItem.Value)
+		}
+	case "Deployment":
+		replaceValue(doc, func(old interface{}) interface{} {
+			return c.placeholder(ValuesPlatform, resourceName, "numInstances", old)
+		}, "spec", "replicas")
+		replaceValue(doc, func(old interface{}) interface{} {
+			return c.placeholder(ValuesPlatform, resourceName, "imagePullPolicy", old)
+		}, "spec", "template", "spec", "containers", 0, "imagePullPolicy")
+		c.parameterizeEnv(resourceName, doc)
+	case "Service":
+		replaceValue(doc, func(old interface{}) interface{} {
+			return c.placeholder(ValuesPlatform, resourceName, "serviceType", old)
+		}, "spec", "type")
+		replaceValue(doc, func(old interface{}) interface{} {
+			return c.placeholder(ValuesPlatform, resourceName, "nodePort", old)
+		}, "spec", "ports", 0, "nodePort")
+	}
+}
+
+// parameterizeEnv replaces the env values of a workload's containers with placeholders. Those whose name says that
+// they hold a secret are required when the chart is installed.
+func (c *chart) parameterizeEnv(resourceName string, doc yaml.MapSlice) {
+	spec, _ := kube.Lookup(doc, "spec").(yaml.MapSlice)
+	template, _ := kube.Lookup(spec, "template").(yaml.MapSlice)
+	podSpec, _ := kube.Lookup(template, "spec").(yaml.MapSlice)
+	containers, _ := kube.Lookup(podSpec, "containers").([]interface{})
+	for _, curContainer := range containers {
+		container, _ := curContainer.(yaml.MapSlice)
+		env, _ := kube.Lookup(container, "env").([]interface{})
+		for _, curEnv := range env {
+			envVar, _ := curEnv.(yaml.MapSlice)
+			name, _ := kube.Lookup(envVar, "name").(string)
+			replaceValue(envVar, func(old interface{}) interface{} {
+				if model.IsSecretParam("environment", name) {
+					return c.requiredPlaceholder(ValuesEnv, resourceName, name, false)
+				}
+				return c.placeholder(ValuesEnv, resourceName, name, old)
+			}, "value")
+		}
+	}
+}
+
+// valuesFile - values.yaml, with the values of every section
+func (c *chart) valuesFile() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# The values of the %s chart, generated by rezolvr\n", c.metadata.Name)
+	fmt.Fprintf(buf, "#  - %s: the params of each environment.properties resource\n", ValuesEnvironment)
+	fmt.Fprintf(buf, "#  - %s: the params of each environment.secret resource\n", ValuesSecrets)
+	fmt.Fprintf(buf, "#  - %s: the platform settings of each workload\n", ValuesPlatform)
+	fmt.Fprintf(buf, "#  - %s: the env values of each workload which aren't read from a ConfigMap or Secret\n", ValuesEnv)
+	fmt.Fprintf(buf, "# Secret values are left empty, so they must be set when the chart is installed\n")
+	fmt.Fprintf(buf, "# (e.g. --set %s.<resource>.<param>=<value>)\n", ValuesSecrets)
+	if len(c.values) == 0 {
+		buf.WriteString("{}\n")
+		return buf.Bytes(), nil
+	}
+	out, err := yaml.Marshal(c.values)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(out)
+	return buf.Bytes(), nil
+}
+
+// replaceValue replaces the value at a path (of map keys and list indexes) with the result of replace, when the
+// object has a value there
+func replaceValue(doc yaml.MapSlice, replace func(old interface{}) interface{}, path ...interface{}) {
+	var node interface{} = doc
+	for i, curKey := range path {
+		last := i == len(path)-1
+		switch key := curKey.(type) {
+		case string:
+			curMap, ok := node.(yaml.MapSlice)
+			if !ok {
+				return
+			}
+			index := -1
+			for j := range curMap {
+				if curMap[j].Key == key {
+					index = j
+					break
+				}
+			}
+			if index < 0 {
+				return
+			}
+			if last {
+				curMap[index].Value = replace(curMap[index].Value)
+				return
+			}
+			node = curMap[index].Value
+		case int:
+			curList, ok := node.([]interface{})
+			if !ok || key >= len(curList) {
+				return
+			}
+			if last {
+				curList[key] = replace(curList[key])
+				return
+			}
+			node = curList[key]
+		}
+	}
+}
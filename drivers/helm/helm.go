@@ -0,0 +1,166 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package helm generates a Helm chart from resolved components. The chart's templates are the objects generated by the
+// Kubernetes driver, with the values which differ between installations moved into values.yaml.
+package helm
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"rezolvr/drivers"
+	"rezolvr/drivers/kube"
+	"rezolvr/model"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Driver is the Helm driver. It implements model.RezolvrDriver.
+type Driver struct{}
+
+// DriverName is the name used to select this driver, in an environment file's 'driver' field
+const DriverName = "helm"
+
+// DriverVersion is the version of this driver
+const DriverVersion = "0.0.1"
+
+// The output formats of this driver
+const (
+	FormatChart    = "helm-chart"
+	FormatValues   = "helm-values"
+	FormatTemplate = "helm-template"
+)
+
+// Platform settings (in the default settings) which describe the chart
+const (
+	SettingChartName    = "chartName"
+	SettingChartVersion = "chartVersion"
+	SettingAppVersion   = "appVersion"
+)
+
+// DefaultChartName - the name of the chart, when neither the 'chartName' setting nor the environment names it
+const DefaultChartName = "rezolvr"
+
+// DefaultChartVersion - the version of the chart, unless the 'chartVersion' setting says otherwise
+const DefaultChartVersion = "0.1.0"
+
+// TemplatesDir - the directory of the chart which holds its templates
+const TemplatesDir = "templates"
+
+// namespaceSettings - platform settings of the Kubernetes driver which are left to Helm: the objects are installed into
+// the release's namespace, and the files are always laid out as a chart
+var namespaceSettings = []string{kube.SettingNamespace, kube.SettingCreateNamespace, kube.SettingLayout}
+
+// chartName - the names of charts (lower case letters, digits and '-')
+var chartName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// invalidChartChars - characters which aren't allowed in the name of a chart
+var invalidChartChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func init() {
+	drivers.Register(DriverName, func() model.RezolvrDriver {
+		return Driver{}
+	})
+}
+
+// logger is supplied by rezolvr, via SetLogger
+var logger = model.GetLogger()
+
+// Name - the name used to select this driver
+func (rd Driver) Name() string {
+	return DriverName
+}
+
+// Version - the version of this driver
+func (rd Driver) Version() string {
+	return DriverVersion
+}
+
+// SetLogger - use rezolvr's logger, so that messages honour its log level and format. The Kubernetes driver, which
+// generates the objects, uses it as well.
+func (rd Driver) SetLogger(newLogger model.Logger) {
+	logger = newLogger
+	kube.Driver{}.SetLogger(newLogger)
+}
+
+// Capabilities - the resource types supported by the Kubernetes driver, and the output formats of this driver
+func (rd Driver) Capabilities() model.Capabilities {
+	return model.Capabilities{APIVersion: model.DriverAPIVersion, ResourceTypes: kube.Driver{}.Capabilities().ResourceTypes,
+		OutputFormats: []string{FormatChart, FormatValues, FormatTemplate}}
+}
+
+// Validate - check that the chart can be named, and that the Kubernetes driver can generate its objects
+func (rd Driver) Validate(ctx context.Context, request *model.TransformRequest) error {
+	if _, err := newChart(request); err != nil {
+		return err
+	}
+	return kube.Driver{}.Validate(ctx, kubeRequest(request))
+}
+
+// kubeRequest - the request for the Kubernetes driver, without the settings which are left to Helm
+func kubeRequest(request *model.TransformRequest) *model.TransformRequest {
+	results := *request
+	results.PlatformSettings = make(map[string]*model.Platform)
+	for k, v := range request.PlatformSettings {
+		params := make(map[string]*model.Param)
+		for paramName, param := range v.Params {
+			params[paramName] = param
+		}
+		for _, curSetting := range namespaceSettings {
+			delete(params, curSetting)
+		}
+		results.PlatformSettings[k] = &model.Platform{Params: params}
+	}
+	return &results
+}
+
+// Transform generates a chart: Chart.yaml, values.yaml, and a template for each file of the Kubernetes driver
+func (rd Driver) Transform(ctx context.Context, request *model.TransformRequest) (*model.TransformResult, error) {
+	result := &model.TransformResult{Artifacts: []model.Artifact{}}
+	if request.UpdatedComponents == nil {
+		logger.Infof("No components / resources to transform")
+		return result, nil
+	}
+	chart, err := newChart(request)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := kube.Driver{}.Transform(ctx, kubeRequest(request))
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]model.Artifact, 0, len(manifests.Artifacts))
+	for _, curManifest := range manifests.Artifacts {
+		content, err := chart.template(strings.TrimSuffix(curManifest.Path, ".yaml"), curManifest.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", curManifest.Path, err)
+		}
+		templates = append(templates, model.Artifact{Path: path.Join(TemplatesDir, curManifest.Path), Format: FormatTemplate, Content: content})
+	}
+	metadata, err := yaml.Marshal(chart.metadata)
+	if err != nil {
+		return nil, err
+	}
+	values, err := chart.valuesFile()
+	if err != nil {
+		return nil, err
+	}
+	result.Artifacts = append(result.Artifacts, model.Artifact{Path: "Chart.yaml", Format: FormatChart, Content: metadata},
+		model.Artifact{Path: "values.yaml", Format: FormatValues, Content: values})
+	result.Artifacts = append(result.Artifacts, templates...)
+	return result, nil
+}
@@ -0,0 +1,145 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"rezolvr/drivers/drivertest"
+	"rezolvr/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRequest() *model.TransformRequest {
+	return &model.TransformRequest{
+		UpdatedComponents: map[string]*model.Component{
+			"resource.web.app:welcome": {Name: "welcome", Type: "resource.web.app",
+				Provides: map[string]*model.Resource{
+					"service.web.app:welcomeapp": {Name: "welcomeapp", Type: "service.web.app", Params: drivertest.Params("imageName", "welcome", "port", "3000")},
+				},
+				Uses: map[string]*model.Resource{
+					"environment": {Type: "environment", Params: map[string]*model.Param{"APP_MSG": {Name: "APP_MSG", Value: "Hello",
+						Formula: `{{with(index .Needs "environment.properties:appEnvProps")}}{{.Params.app_message.Value}}{{end}}`}}},
+				},
+			},
+		},
+		State: &model.State{Components: map[string]*model.Component{
+			"environment.properties": {Name: "environment", Provides: map[string]*model.Resource{
+				"environment.properties:appEnvProps": {Name: "appEnvProps", Type: "environment.properties", Params: drivertest.Params("app_message", "Hello")},
+				"environment.secret:creds":           {Name: "creds", Type: "environment.secret", Params: drivertest.Params("password", "s3cret")},
+			}},
+		}},
+		PlatformSettings: map[string]*model.Platform{
+			"default": {Params: drivertest.Params("numInstances", "2", "imagePullPolicy", "Never", "namespace", "shop")},
+		},
+		Environment: "devMinikubeEnv",
+	}
+}
+
+func Test_Transform(t *testing.T) {
+	result, err := Driver{}.Transform(context.Background(), testRequest())
+	assert.Nil(t, err)
+	paths := make([]string, 0, len(result.Artifacts))
+	for _, curArtifact := range result.Artifacts {
+		paths = append(paths, curArtifact.Path)
+	}
+	// The namespace is left to Helm
+	assert.Equal(t, []string{"Chart.yaml", "values.yaml", "templates/appEnvProps.yaml", "templates/creds.yaml", "templates/welcomeapp.yaml"}, paths)
+	assert.Equal(t, `apiVersion: v2
+name: devminikubeenv
+description: Generated by rezolvr for the devMinikubeEnv environment
+type: application
+version: 0.1.0
+`, string(result.Artifacts[0].Content))
+	assert.Equal(t, `# The values of the devminikubeenv chart, generated by rezolvr
+#  - environment: the params of each environment.properties resource
+#  - secrets: the params of each environment.secret resource
+#  - platform: the platform settings of each workload
+#  - env: the env values of each workload which aren't read from a ConfigMap or Secret
+# Secret values are left empty, so they must be set when the chart is installed
+# (e.g. --set secrets.<resource>.<param>=<value>)
+environment:
+  appEnvProps:
+    app_message: Hello
+platform:
+  welcomeapp:
+    imagePullPolicy: Never
+    numInstances: 2
+    serviceType: NodePort
+secrets:
+  creds:
+    password: ""
+`, string(result.Artifacts[1].Content))
+	assert.Contains(t, string(result.Artifacts[2].Content), `  app_message: {{ (index .Values.environment "appEnvProps" "app_message") | quote }}`)
+	assert.Contains(t, string(result.Artifacts[3].Content),
+		`  password: {{ required "secrets.creds.password is required" (index .Values.secrets "creds" "password") | b64enc | quote }}`)
+	assert.NotContains(t, string(result.Artifacts[3].Content), "s3cret")
+	workload := string(result.Artifacts[4].Content)
+	assert.Contains(t, workload, `  replicas: {{ (index .Values.platform "welcomeapp" "numInstances") }}`)
+	assert.Contains(t, workload, `imagePullPolicy: {{ (index .Values.platform "welcomeapp" "imagePullPolicy") | quote }}`)
+	assert.Contains(t, workload, `  type: {{ (index .Values.platform "welcomeapp" "serviceType") | quote }}`)
+	assert.Contains(t, workload, "configMapKeyRef")
+	assert.NotContains(t, workload, "namespace:")
+}
+
+// Test_SecretValues - no secret value is written to the chart, even when it's declared as a property or set directly
+func Test_SecretValues(t *testing.T) {
+	request := testRequest()
+	request.State.Components["environment.properties"].Provides["environment.properties:dbEnvProps"] = &model.Resource{
+		Name: "dbEnvProps", Type: "environment.properties", Params: drivertest.Params("db_name", "catalog", "db_password", "passwordie")}
+	environment := request.UpdatedComponents["resource.web.app:welcome"].Uses["environment"].Params
+	environment["DB_PW"] = &model.Param{Name: "DB_PW", Value: "passwordie",
+		Formula: `{{with(index .Needs "environment.properties:dbEnvProps")}}{{.Params.db_password.Value}}{{end}}`}
+	environment["API_TOKEN"] = &model.Param{Name: "API_TOKEN", Value: "t0ken"}
+	environment["DB_CONN"] = &model.Param{Name: "DB_CONN", Value: "s3cret"}
+	environment["LOG_LEVEL"] = &model.Param{Name: "LOG_LEVEL", Value: "debug"}
+	result, err := Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, result.Artifacts)
+	for _, curArtifact := range result.Artifacts {
+		for _, curSecret := range []string{"passwordie", "t0ken", "s3cret"} {
+			assert.NotContains(t, string(curArtifact.Content), curSecret, curArtifact.Path)
+		}
+	}
+	values := string(result.Artifacts[1].Content)
+	assert.Contains(t, values, "    db_password: \"\"\n")
+	assert.Contains(t, values, "env:\n  welcomeapp:\n    API_TOKEN: \"\"\n    DB_CONN: \"\"\n    LOG_LEVEL: debug\n")
+	for _, curArtifact := range result.Artifacts {
+		switch curArtifact.Path {
+		case "templates/dbEnvProps.yaml":
+			assert.Contains(t, string(curArtifact.Content),
+				`  db_password: {{ required "environment.dbEnvProps.db_password is required" (index .Values.environment "dbEnvProps" "db_password") | quote }}`)
+		case "templates/welcomeapp.yaml":
+			assert.Contains(t, string(curArtifact.Content),
+				`value: {{ required "env.welcomeapp.API_TOKEN is required" (index .Values.env "welcomeapp" "API_TOKEN") | quote }}`)
+			assert.Contains(t, string(curArtifact.Content), `value: {{ (index .Values.env "welcomeapp" "LOG_LEVEL") | quote }}`)
+		}
+	}
+}
+
+func Test_ChartName(t *testing.T) {
+	request := testRequest()
+	request.PlatformSettings["default"].Params["chartName"] = &model.Param{Name: "chartName", Value: "Shop_App"}
+	err := Driver{}.Validate(context.Background(), request)
+	assert.EqualError(t, err, "'Shop_App' is not a valid chart name (use lower case letters, digits and '-')")
+
+	request.PlatformSettings["default"].Params["chartName"].Value = "shop"
+	request.PlatformSettings["default"].Params["chartVersion"] = &model.Param{Name: "chartVersion", Value: "1.2.0"}
+	chart, err := newChart(request)
+	assert.Nil(t, err)
+	assert.Equal(t, "shop", chart.metadata.Name)
+	assert.Equal(t, "1.2.0", chart.metadata.Version)
+}
@@ -0,0 +1,418 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"fmt"
+	"rezolvr/drivers"
+	"rezolvr/model"
+	"sort"
+	"strconv"
+)
+
+// buildContext - everything a builder needs to generate the objects for a provided resource
+type buildContext struct {
+	component *model.Component
+	provides  *model.Resource
+	// platform - the default platform settings, overridden by those specific to the resource
+	platform map[string]*model.Param
+	// provided - the resources provided by every component, which the objects may refer to. Not checked when nil.
+	provided *providedResources
+	// metadata - the namespace, labels and annotations of the objects, from resolveMetadata
+	metadata *objectMetadata
+	// overlay - the namespace, labels and annotations which are applied by a kustomize overlay, rather than to each object
+	overlay *objectMetadata
+	// ingress - the path of a web app which is exposed by an Ingress, which is shared with other web apps
+	ingress *ingressPath
+}
+
+// providedResources - the resources provided by the components, which a workload may refer to
+type providedResources struct {
+	// claims - the names of the storage claims
+	claims map[string]bool
+	// environment - the environment.secret and environment.properties resources, by ID (e.g. environment.secret:creds)
+	environment map[string]*model.Resource
+	// resources - every provided resource, by ID, so that the params copied by a formula can be traced
+	resources map[string]*model.Resource
+}
+
+// builder generates the Kubernetes objects for a provided resource, in the order they should be written
+type builder func(bc *buildContext) ([]interface{}, error)
+
+// builders - the resource types which are generated as typed objects. Other types fall back to a template.
+var builders = map[string]builder{
+	"environment.properties": buildConfigMap,
+	"environment.secret":     buildSecret,
+	"service.web.app":        buildWebApp,
+	"service.db.postgres":    buildPostgres,
+	"service":                buildService,
+	"storage.volume":         buildPersistentVolume,
+	"storage.volume-claim":   buildPersistentVolumeClaim,
+}
+
+// builderTypes - the resource types with a builder, sorted
+func builderTypes() []string {
+	results := make([]string, 0, len(builders))
+	for k := range builders {
+		results = append(results, k)
+	}
+	sort.Strings(results)
+	return results
+}
+
+func (bc *buildContext) resourceID() string {
+	return drivers.ResourceID(bc.provides)
+}
+
+// param - the value of a provided param, or an empty string when it isn't set
+func (bc *buildContext) param(name string) string {
+	return drivers.ParamValue(bc.provides.Params, name)
+}
+
+// requiredParam - the value of a provided param, which must be set
+func (bc *buildContext) requiredParam(name string) (string, error) {
+	return drivers.RequiredParam(bc.provides, name)
+}
+
+// platformParam - the value of a platform setting, or an empty string when it isn't set
+func (bc *buildContext) platformParam(name string) string {
+	return drivers.ParamValue(bc.platform, name)
+}
+
+// port parses a port number, which must be set
+func (bc *buildContext) port(name string) (int32, error) {
+	value, err := bc.requiredParam(name)
+	if err != nil {
+		return 0, err
+	}
+	return bc.parseInt32(name, value)
+}
+
+func (bc *buildContext) parseInt32(name string, value string) (int32, error) {
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s: '%s' is not a valid number for '%s'", bc.resourceID(), value, name)
+	}
+	return int32(parsed), nil
+}
+
+// buildWebApp - a workload, which may be exposed by an OpenShift Route or an Ingress (see the 'expose' platform
+// setting). An exposed web app's Service is only reachable within the cluster, unless the 'serviceType' says otherwise.
+func buildWebApp(bc *buildContext) ([]interface{}, error) {
+	expose, err := bc.expose()
+	if err != nil {
+		return nil, err
+	}
+	defaultServiceType := "NodePort"
+	if len(expose) > 0 {
+		defaultServiceType = "ClusterIP"
+	}
+	objects, err := buildWorkload(bc, "port", defaultServiceType)
+	if err != nil {
+		return nil, err
+	}
+	service := objects[1].(*Service)
+	switch expose {
+	case ExposeRoute:
+		route, err := bc.route(service)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, route)
+	case ExposeIngress:
+		// The Ingress is generated once the paths of every web app are known
+		if bc.ingress, err = bc.ingressPath(service); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+func buildPostgres(bc *buildContext) ([]interface{}, error) {
+	return buildWorkload(bc, "db_port", "ClusterIP")
+}
+
+// buildWorkload - a Deployment running the resource's image, and a Service exposing its port, both named after the
+// resource (so that a component may provide several workloads). The service type can be
+// overridden with the 'serviceType' platform setting. The component's deployment hints may add a PodDisruptionBudget
+// (minAvailable) and a HorizontalPodAutoscaler (maxInstances), which replaces the Deployment's fixed replicas.
+func buildWorkload(bc *buildContext, portParam string, defaultServiceType string) ([]interface{}, error) {
+	deployment, err := bc.deployment(portParam)
+	if err != nil {
+		return nil, err
+	}
+	service, err := bc.workloadService(portParam, defaultServiceType)
+	if err != nil {
+		return nil, err
+	}
+	objects := []interface{}{deployment, service}
+	budget, err := bc.disruptionBudget(deployment)
+	if err != nil {
+		return nil, err
+	}
+	if budget != nil {
+		objects = append(objects, budget)
+	}
+	autoscaler, err := bc.autoscaler(deployment)
+	if err != nil {
+		return nil, err
+	}
+	if autoscaler != nil {
+		// Otherwise, every apply would reset the number of replicas chosen by the autoscaler
+		deployment.Spec.Replicas = nil
+		objects = append(objects, autoscaler)
+	}
+	return objects, nil
+}
+
+func (bc *buildContext) deployment(portParam string) (*Deployment, error) {
+	image, err := bc.requiredParam("imageName")
+	if err != nil {
+		return nil, err
+	}
+	port, err := bc.port(portParam)
+	if err != nil {
+		return nil, err
+	}
+	selector := map[string]string{"app": bc.provides.Name}
+	container := Container{
+		Name:            bc.provides.Name,
+		Image:           image,
+		ImagePullPolicy: bc.platformParam("imagePullPolicy"),
+		Ports:           []ContainerPort{{ContainerPort: port}},
+	}
+	if container.Env, container.EnvFrom, err = bc.env(); err != nil {
+		return nil, err
+	}
+	deployment := &Deployment{
+		TypeMeta: TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		Metadata: bc.objectMeta(bc.provides.Name, true),
+		Spec: DeploymentSpec{
+			Selector: LabelSelector{MatchLabels: selector},
+			Template: PodTemplateSpec{
+				Metadata: ObjectMeta{Labels: bc.podLabels(selector)},
+				Spec:     PodSpec{Containers: []Container{container}},
+			},
+		},
+	}
+	if numInstances := bc.platformParam("numInstances"); len(numInstances) > 0 {
+		replicas, err := bc.parseInt32("numInstances", numInstances)
+		if err != nil {
+			return nil, err
+		}
+		deployment.Spec.Replicas = &replicas
+	}
+	if err := bc.applyHints(deployment, port); err != nil {
+		return nil, err
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	if podSpec.Containers[0].VolumeMounts, podSpec.Volumes, err = bc.volumes(); err != nil {
+		return nil, err
+	}
+	return deployment, nil
+}
+
+// storageMount - a 'storage' resource used by a component, which is mounted into its container
+type storageMount struct {
+	volumeName string
+	claimName  string
+	mountPath  string
+	readOnly   bool
+	subPath    string
+}
+
+// storageMounts - the 'storage' resources used by the component, sorted by their key. Each one names a claim
+// ('volumeClaimName', or the 'volumeName' followed by 'claim'), and where it's mounted ('mountPath', and optionally
+// 'readOnly' and 'subPath'). The volume is named by 'volumeName', or after the claim.
+func (bc *buildContext) storageMounts() ([]storageMount, error) {
+	var results []storageMount
+	for _, curUses := range drivers.SortedUses(bc.component) {
+		if curUses.Type != "storage" {
+			continue
+		}
+		mount := storageMount{
+			volumeName: drivers.ParamValue(curUses.Params, "volumeName"),
+			claimName:  drivers.ParamValue(curUses.Params, "volumeClaimName"),
+			mountPath:  drivers.ParamValue(curUses.Params, "mountPath"),
+			subPath:    drivers.ParamValue(curUses.Params, "subPath"),
+		}
+		if len(mount.claimName) == 0 && len(mount.volumeName) > 0 {
+			mount.claimName = mount.volumeName + "claim"
+		}
+		if len(mount.claimName) == 0 {
+			return nil, fmt.Errorf("%s: the storage used by %s doesn't name a claim (set 'volumeClaimName' or 'volumeName')", bc.resourceID(), bc.component.Name)
+		}
+		if len(mount.volumeName) == 0 {
+			mount.volumeName = mount.claimName
+		}
+		if len(mount.mountPath) == 0 {
+			return nil, fmt.Errorf("%s: the storage used by %s doesn't have a 'mountPath'", bc.resourceID(), bc.component.Name)
+		}
+		if readOnly := drivers.ParamValue(curUses.Params, "readOnly"); len(readOnly) > 0 {
+			var err error
+			if mount.readOnly, err = strconv.ParseBool(readOnly); err != nil {
+				return nil, fmt.Errorf("%s: '%s' is not a valid value for 'readOnly' (use true or false)", bc.resourceID(), readOnly)
+			}
+		}
+		results = append(results, mount)
+	}
+	return results, nil
+}
+
+// volumes - a volume mount for each 'storage' resource used by the component, and a volume for each claim. A claim
+// which is mounted more than once (e.g. with different sub-paths) has a single volume.
+func (bc *buildContext) volumes() ([]VolumeMount, []Volume, error) {
+	mounts, err := bc.storageMounts()
+	if err != nil {
+		return nil, nil, err
+	}
+	var volumeMounts []VolumeMount
+	var volumes []Volume
+	claims := make(map[string]string)
+	for _, curMount := range mounts {
+		if bc.provided != nil && !bc.provided.claims[curMount.claimName] {
+			return nil, nil, fmt.Errorf("%s: the claim '%s' isn't provided by any component (no storage.volume-claim is named '%s')",
+				bc.resourceID(), curMount.claimName, curMount.claimName)
+		}
+		if claimName, exists := claims[curMount.volumeName]; exists {
+			if claimName != curMount.claimName {
+				return nil, nil, fmt.Errorf("%s: the volume '%s' is used for more than one claim ('%s' and '%s')",
+					bc.resourceID(), curMount.volumeName, claimName, curMount.claimName)
+			}
+		} else {
+			claims[curMount.volumeName] = curMount.claimName
+			volumes = append(volumes, Volume{Name: curMount.volumeName, PersistentVolumeClaim: &ClaimVolumeSource{ClaimName: curMount.claimName}})
+		}
+		volumeMounts = append(volumeMounts, VolumeMount{MountPath: curMount.mountPath, Name: curMount.volumeName,
+			ReadOnly: curMount.readOnly, SubPath: curMount.subPath})
+	}
+	return volumeMounts, volumes, nil
+}
+
+// indexProvided - the name of every storage.volume-claim provided by the components (its 'name' param, or the name of
+// the resource), and every environment.secret and environment.properties resource
+func indexProvided(components map[string]*model.Component) *providedResources {
+	results := &providedResources{claims: make(map[string]bool), environment: make(map[string]*model.Resource),
+		resources: drivers.ProvidedResources(components)}
+	for resID, curProvides := range results.resources {
+		switch curProvides.Type {
+		case "storage.volume-claim":
+			results.claims[drivers.ProvidedName(curProvides)] = true
+		case "environment.secret", "environment.properties":
+			results.environment[resID] = curProvides
+		}
+	}
+	return results
+}
+
+func (bc *buildContext) workloadService(portParam string, defaultServiceType string) (*Service, error) {
+	port, err := bc.port(portParam)
+	if err != nil {
+		return nil, err
+	}
+	servicePort := ServicePort{Protocol: "TCP", Port: port, TargetPort: port}
+	if nodePort := bc.platformParam("nodePort"); len(nodePort) > 0 {
+		if servicePort.NodePort, err = bc.parseInt32("nodePort", nodePort); err != nil {
+			return nil, err
+		}
+	}
+	serviceType := bc.platformParam("serviceType")
+	if len(serviceType) == 0 {
+		serviceType = defaultServiceType
+	}
+	metadata := bc.objectMeta(bc.provides.Name+"-service", true)
+	metadata.Labels["app"] = bc.provides.Name
+	return &Service{
+		TypeMeta: TypeMeta{APIVersion: "v1", Kind: "Service"},
+		Metadata: metadata,
+		Spec: ServiceSpec{
+			Type:     serviceType,
+			Selector: map[string]string{"app": bc.provides.Name},
+			Ports:    []ServicePort{servicePort},
+		},
+	}, nil
+}
+
+// buildService - a Service named by the resource's 'name' param
+func buildService(bc *buildContext) ([]interface{}, error) {
+	name, err := bc.requiredParam("name")
+	if err != nil {
+		return nil, err
+	}
+	port, err := bc.port("port")
+	if err != nil {
+		return nil, err
+	}
+	service := &Service{
+		TypeMeta: TypeMeta{APIVersion: "v1", Kind: "Service"},
+		Metadata: bc.objectMeta(name, true),
+		Spec: ServiceSpec{
+			Selector: map[string]string{"app": name},
+			Ports:    []ServicePort{{Port: port, TargetPort: port}},
+		},
+	}
+	return []interface{}{service}, nil
+}
+
+func buildPersistentVolume(bc *buildContext) ([]interface{}, error) {
+	name, err := bc.requiredParam("name")
+	if err != nil {
+		return nil, err
+	}
+	volume := &PersistentVolume{
+		TypeMeta: TypeMeta{APIVersion: "v1", Kind: "PersistentVolume"},
+		Metadata: bc.objectMeta(name, false),
+		Spec: PersistentVolumeSpec{
+			VolumeMode:                    bc.param("volumeMode"),
+			AccessModes:                   bc.accessModes(),
+			PersistentVolumeReclaimPolicy: bc.param("persistentVolumeReclaimPolicy"),
+			StorageClassName:              bc.param("storageClassName"),
+		},
+	}
+	if volumeSize := bc.param("volumeSize"); len(volumeSize) > 0 {
+		volume.Spec.Capacity = map[string]string{"storage": volumeSize}
+	}
+	if hostPath := bc.param("hostPath"); len(hostPath) > 0 {
+		volume.Spec.HostPath = &HostPathVolumeSource{Path: hostPath}
+	}
+	return []interface{}{volume}, nil
+}
+
+func buildPersistentVolumeClaim(bc *buildContext) ([]interface{}, error) {
+	name, err := bc.requiredParam("name")
+	if err != nil {
+		return nil, err
+	}
+	claim := &PersistentVolumeClaim{
+		TypeMeta: TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+		Metadata: bc.objectMeta(name, true),
+		Spec: PersistentVolumeClaimSpec{
+			StorageClassName: bc.param("storageClassName"),
+			VolumeMode:       bc.param("volumeMode"),
+			AccessModes:      bc.accessModes(),
+		},
+	}
+	if requestSize := bc.param("volumeRequestSize"); len(requestSize) > 0 {
+		claim.Spec.Resources.Requests = map[string]string{"storage": requestSize}
+	}
+	return []interface{}{claim}, nil
+}
+
+func (bc *buildContext) accessModes() []string {
+	if accessModes := bc.param("accessModes"); len(accessModes) > 0 {
+		return []string{accessModes}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"rezolvr/drivers"
+	"rezolvr/model"
+	"strings"
+)
+
+// paramReference matches a formula which copies a single param of a needed resource, e.g.
+// {{with(index .Needs "environment.secret:creds")}}{{.Params.db_password.Value}}{{end}}
+var paramReference = regexp.MustCompile(`^\{\{\s*with\s*\(?\s*index\s+\.Needs\s+"([^"]+)"\s*\)?\s*\}\}` +
+	`\{\{\s*\.Params\.([A-Za-z0-9_]+)\.Value\s*\}\}\{\{\s*end\s*\}\}$`)
+
+// maxReferenceDepth - how many resources are followed to find where a param's value comes from, so that a cycle
+// of formulas ends
+const maxReferenceDepth = 16
+
+// validKey - the keys allowed within a Secret or ConfigMap
+var validKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// invalidNameChars - characters which aren't allowed in the name of a Secret or ConfigMap (a DNS subdomain)
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// objectName converts the name of a resource (e.g. dbEnvProps) to a valid object name (dbenvprops)
+func objectName(name string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+}
+
+// environmentData - the params of an environment resource, which must have valid keys
+func (bc *buildContext) environmentData() (map[string]string, error) {
+	data := make(map[string]string)
+	for _, curParam := range bc.provides.Params {
+		if !validKey.MatchString(curParam.Name) {
+			return nil, fmt.Errorf("%s: '%s' is not a valid key (use letters, digits, '-', '_' or '.')", bc.resourceID(), curParam.Name)
+		}
+		data[curParam.Name] = curParam.Value
+	}
+	return data, nil
+}
+
+// buildSecret - a Secret holding the params of an environment.secret resource
+func buildSecret(bc *buildContext) ([]interface{}, error) {
+	data, err := bc.environmentData()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range data {
+		data[k] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	secret := &Secret{
+		TypeMeta: TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		Metadata: bc.objectMeta(objectName(bc.provides.Name), true),
+		Type:     "Opaque",
+		Data:     data,
+	}
+	return []interface{}{secret}, nil
+}
+
+// buildConfigMap - a ConfigMap holding the params of an environment.properties resource
+func buildConfigMap(bc *buildContext) ([]interface{}, error) {
+	data, err := bc.environmentData()
+	if err != nil {
+		return nil, err
+	}
+	configMap := &ConfigMap{
+		TypeMeta: TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		Metadata: bc.objectMeta(objectName(bc.provides.Name), true),
+		Data:     data,
+	}
+	return []interface{}{configMap}, nil
+}
+
+// keyRef refers to a key within the Secret or ConfigMap generated for an environment resource
+func keyRef(resourceType string, name string, key string) *EnvVarSource {
+	selector := &KeySelector{Name: objectName(name), Key: key}
+	if resourceType == "environment.secret" || resourceType == "secret" {
+		return &EnvVarSource{SecretKeyRef: selector}
+	}
+	return &EnvVarSource{ConfigMapKeyRef: selector}
+}
+
+// env - the environment variables of a workload, from the resources it uses:
+//   - 'environment' - each param is an environment variable. A param whose formula copies a value of an
+//     environment.secret or environment.properties resource (directly, or through other resources) refers to its Secret
+//     or ConfigMap; other values are inlined
+//   - 'environment.secret' / 'environment.properties' (named after the resource) - without params, every key is used
+//     (envFrom). Otherwise, each param is an environment variable, and its value names the key
+//   - 'secret' - as above, for a Secret which isn't necessarily generated by rezolvr
+func (bc *buildContext) env() ([]EnvVar, []EnvFromSource, error) {
+	var env []EnvVar
+	var envFrom []EnvFromSource
+	for _, curUses := range drivers.SortedUses(bc.component) {
+		switch curUses.Type {
+		case "environment.secret", "environment.properties":
+			if len(curUses.Params) == 0 {
+				ref := &LocalObjectReference{Name: objectName(curUses.Name)}
+				if curUses.Type == "environment.secret" {
+					envFrom = append(envFrom, EnvFromSource{SecretRef: ref})
+				} else {
+					envFrom = append(envFrom, EnvFromSource{ConfigMapRef: ref})
+				}
+				continue
+			}
+		case "environment", "secret":
+		default:
+			continue
+		}
+		for _, curParam := range drivers.SortedParams(curUses.Params) {
+			envVar, err := bc.envVar(curUses, curParam)
+			if err != nil {
+				return nil, nil, err
+			}
+			env = append(env, envVar)
+		}
+	}
+	return env, envFrom, nil
+}
+
+func (bc *buildContext) envVar(curUses *model.Resource, curParam *model.Param) (EnvVar, error) {
+	// A value copied from an environment resource is referenced, rather than repeated within the Deployment
+	if ref := bc.environmentRef(curParam.Formula); ref != nil {
+		return EnvVar{Name: curParam.Name, ValueFrom: ref}, nil
+	}
+	if curUses.Type == "environment" {
+		return EnvVar{Name: curParam.Name, Value: curParam.Value}, nil
+	}
+	if len(curUses.Name) == 0 {
+		return EnvVar{}, fmt.Errorf("%s: the %s used by %s must be named after the resource which holds its values", bc.resourceID(),
+			curUses.Type, bc.component.Name)
+	}
+	key := curParam.Value
+	if len(key) == 0 {
+		key = curParam.Name
+	}
+	return EnvVar{Name: curParam.Name, ValueFrom: keyRef(curUses.Type, curUses.Name, key)}, nil
+}
+
+// environmentRef follows a formula back to where its value comes from. When it copies a param of an environment.secret
+// or environment.properties resource (directly, or through the params of other provided resources, e.g. a database
+// which copies its password from a secret), the key within its Secret or ConfigMap is referenced. Otherwise, nil.
+func (bc *buildContext) environmentRef(formula string) *EnvVarSource {
+	if bc.provided == nil {
+		return nil
+	}
+	for depth := 0; depth < maxReferenceDepth; depth++ {
+		match := paramReference.FindStringSubmatch(strings.TrimSpace(formula))
+		if match == nil {
+			return nil
+		}
+		if resource := bc.provided.environment[match[1]]; resource != nil {
+			if resource.Params[match[2]] == nil {
+				return nil
+			}
+			return keyRef(resource.Type, resource.Name, match[2])
+		}
+		resource := bc.provided.resources[match[1]]
+		if resource == nil || resource.Params[match[2]] == nil {
+			return nil
+		}
+		formula = resource.Params[match[2]].Formula
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"fmt"
+	"regexp"
+	"rezolvr/model"
+	"strings"
+)
+
+// DefaultTargetCPUUtilization - the average CPU utilization (percent) an autoscaler aims for, unless a hint says otherwise
+const DefaultTargetCPUUtilization = 80
+
+// ZoneTopologyKey - the node label which identifies a node's zone
+const ZoneTopologyKey = "topology.kubernetes.io/zone"
+
+// quantityFormat - a Kubernetes resource quantity, e.g. 512Mi, 4G or 500m
+var quantityFormat = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(m|k|M|G|T|P|E|Ki|Mi|Gi|Ti|Pi|Ei)?$`)
+
+// byteUnits - units which are commonly used for memory (e.g. 4GB), and the Kubernetes units they're converted to
+var byteUnits = map[string]string{"KB": "Ki", "MB": "Mi", "GB": "Gi", "TB": "Ti", "PB": "Pi", "EB": "Ei"}
+
+// hints - the deployment hints of the component, which may not have any
+func (bc *buildContext) hints() *model.DeploymentHints {
+	if bc.component.DeploymentHints == nil {
+		return &model.DeploymentHints{}
+	}
+	return bc.component.DeploymentHints
+}
+
+// numericHint - the name and value of a hint which can't be negative
+type numericHint struct {
+	name  string
+	value int
+}
+
+// validateHints checks that the numeric hints aren't negative
+func (bc *buildContext) validateHints() error {
+	hints := bc.hints()
+	numbers := []numericHint{
+		{"instances", hints.Instances}, {"minInstances", hints.MinInstances}, {"maxInstances", hints.MaxInstances},
+		{"targetCPUUtilization", hints.TargetCPUUtilization}, {"minAvailable", hints.MinAvailable},
+		{"availabilityZones", hints.AvailabilityZones},
+	}
+	if healthCheck := hints.HealthCheck; healthCheck != nil {
+		numbers = append(numbers, numericHint{"healthCheck.port", healthCheck.Port},
+			numericHint{"healthCheck.initialDelaySeconds", healthCheck.InitialDelaySeconds},
+			numericHint{"healthCheck.periodSeconds", healthCheck.PeriodSeconds},
+			numericHint{"healthCheck.failureThreshold", healthCheck.FailureThreshold})
+	}
+	for _, curNumber := range numbers {
+		if curNumber.value < 0 {
+			return fmt.Errorf("%s: the '%s' deployment hint of %s can't be negative", bc.resourceID(), curNumber.name, bc.component.Name)
+		}
+	}
+	return nil
+}
+
+// quantity converts a memory or CPU hint to a Kubernetes quantity. Byte units (e.g. 4GB) are read as binary units (4Gi).
+func (bc *buildContext) quantity(name string, value string) (string, error) {
+	for unit, kubeUnit := range byteUnits {
+		if strings.HasSuffix(value, unit) {
+			value = strings.TrimSuffix(value, unit) + kubeUnit
+			break
+		}
+	}
+	if !quantityFormat.MatchString(value) {
+		return "", fmt.Errorf("%s: '%s' is not a valid value for the '%s' deployment hint of %s (e.g. 512Mi, 4GB or 500m)",
+			bc.resourceID(), value, name, bc.component.Name)
+	}
+	return value, nil
+}
+
+// resources - the requests and limits of a container, from the minRam / maxRam and minCpu / maxCpu hints
+func (bc *buildContext) resources() (ResourceRequirements, error) {
+	hints := bc.hints()
+	resources := ResourceRequirements{}
+	for _, curHint := range []struct {
+		name, value string
+		values      *map[string]string
+		resource    string
+	}{
+		{"minRam", hints.MinRAM, &resources.Requests, "memory"},
+		{"minCpu", hints.MinCPU, &resources.Requests, "cpu"},
+		{"maxRam", hints.MaxRAM, &resources.Limits, "memory"},
+		{"maxCpu", hints.MaxCPU, &resources.Limits, "cpu"},
+	} {
+		if len(curHint.value) == 0 {
+			continue
+		}
+		value, err := bc.quantity(curHint.name, curHint.value)
+		if err != nil {
+			return ResourceRequirements{}, err
+		}
+		if *curHint.values == nil {
+			*curHint.values = make(map[string]string)
+		}
+		(*curHint.values)[curHint.resource] = value
+	}
+	return resources, nil
+}
+
+// probe - the readiness and liveness probe of a container, from the healthCheck hint
+func (bc *buildContext) probe(containerPort int32) *Probe {
+	healthCheck := bc.hints().HealthCheck
+	if healthCheck == nil {
+		return nil
+	}
+	port := containerPort
+	if healthCheck.Port > 0 {
+		port = int32(healthCheck.Port)
+	}
+	probe := &Probe{
+		InitialDelaySeconds: int32(healthCheck.InitialDelaySeconds),
+		PeriodSeconds:       int32(healthCheck.PeriodSeconds),
+		FailureThreshold:    int32(healthCheck.FailureThreshold),
+	}
+	if len(healthCheck.Path) > 0 {
+		probe.HTTPGet = &HTTPGetAction{Path: healthCheck.Path, Port: port}
+	} else {
+		probe.TCPSocket = &TCPSocketAction{Port: port}
+	}
+	return probe
+}
+
+// applyHints sets the replicas (unless the platform settings do), resources, probes and topology of a Deployment
+func (bc *buildContext) applyHints(deployment *Deployment, containerPort int32) error {
+	if err := bc.validateHints(); err != nil {
+		return err
+	}
+	hints := bc.hints()
+	if deployment.Spec.Replicas == nil && hints.Instances > 0 {
+		replicas := int32(hints.Instances)
+		deployment.Spec.Replicas = &replicas
+	}
+	podSpec := &deployment.Spec.Template.Spec
+	resources, err := bc.resources()
+	if err != nil {
+		return err
+	}
+	podSpec.Containers[0].Resources = resources
+	podSpec.Containers[0].ReadinessProbe = bc.probe(containerPort)
+	podSpec.Containers[0].LivenessProbe = bc.probe(containerPort)
+	if hints.AvailabilityZones > 1 {
+		// Spread the pods as evenly as possible, without preventing them from running on a single-zone cluster
+		podSpec.TopologySpreadConstraints = []TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       ZoneTopologyKey,
+			WhenUnsatisfiable: "ScheduleAnyway",
+			LabelSelector:     deployment.Spec.Selector,
+		}}
+	}
+	return nil
+}
+
+// disruptionBudget - a PodDisruptionBudget which keeps minAvailable pods running, when the hint is set
+func (bc *buildContext) disruptionBudget(deployment *Deployment) (*PodDisruptionBudget, error) {
+	hints := bc.hints()
+	if hints.MinAvailable == 0 {
+		return nil, nil
+	}
+	replicas := 1
+	if deployment.Spec.Replicas != nil {
+		replicas = int(*deployment.Spec.Replicas)
+	}
+	if hints.MaxInstances > 0 && hints.MinInstances > 0 {
+		replicas = hints.MinInstances
+	}
+	if hints.MinAvailable >= replicas {
+		return nil, fmt.Errorf("%s: the 'minAvailable' deployment hint of %s (%d) must be less than the number of instances (%d)",
+			bc.resourceID(), bc.component.Name, hints.MinAvailable, replicas)
+	}
+	minAvailable := int32(hints.MinAvailable)
+	return &PodDisruptionBudget{
+		TypeMeta: TypeMeta{APIVersion: "policy/v1", Kind: "PodDisruptionBudget"},
+		Metadata: bc.objectMeta(deployment.Metadata.Name, true),
+		Spec:     PodDisruptionBudgetSpec{Selector: deployment.Spec.Selector, MinAvailable: &minAvailable},
+	}, nil
+}
+
+// autoscaler - a HorizontalPodAutoscaler, when the maxInstances hint is set. Scaling is based on CPU utilization. The
+// Deployment's replicas are left to the autoscaler (see buildWorkload).
+func (bc *buildContext) autoscaler(deployment *Deployment) (*HorizontalPodAutoscaler, error) {
+	hints := bc.hints()
+	if hints.MaxInstances == 0 {
+		return nil, nil
+	}
+	minReplicas := int32(1)
+	if hints.MinInstances > 0 {
+		minReplicas = int32(hints.MinInstances)
+	} else if deployment.Spec.Replicas != nil {
+		minReplicas = *deployment.Spec.Replicas
+	}
+	if int32(hints.MaxInstances) < minReplicas {
+		return nil, fmt.Errorf("%s: the 'maxInstances' deployment hint of %s (%d) is less than the minimum number of instances (%d)",
+			bc.resourceID(), bc.component.Name, hints.MaxInstances, minReplicas)
+	}
+	if len(hints.MinCPU) == 0 {
+		logger.Warnf("%s: %s is scaled by CPU utilization, but doesn't request any CPU (set the 'minCpu' deployment hint)",
+			bc.resourceID(), bc.component.Name)
+	}
+	targetUtilization := int32(DefaultTargetCPUUtilization)
+	if hints.TargetCPUUtilization > 0 {
+		targetUtilization = int32(hints.TargetCPUUtilization)
+	}
+	return &HorizontalPodAutoscaler{
+		TypeMeta: TypeMeta{APIVersion: "autoscaling/v2", Kind: "HorizontalPodAutoscaler"},
+		Metadata: bc.objectMeta(deployment.Metadata.Name, true),
+		Spec: HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: CrossVersionObjectReference{APIVersion: deployment.APIVersion, Kind: deployment.Kind, Name: deployment.Metadata.Name},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    int32(hints.MaxInstances),
+			Metrics: []MetricSpec{{Type: "Resource", Resource: &ResourceMetricSource{Name: "cpu",
+				Target: MetricTarget{Type: "Utilization", AverageUtilization: &targetUtilization}}}},
+		},
+	}, nil
+}
@@ -0,0 +1,198 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Platform settings which expose a web app outside of the cluster
+const (
+	// SettingExpose - 'ingress' or 'route' (OpenShift). Web apps aren't exposed when it isn't set.
+	SettingExpose = "expose"
+	// SettingIngressHost - the host of the web app, unless the resource has a 'host' param
+	SettingIngressHost         = "ingress.host"
+	SettingIngressClassName    = "ingress.className"
+	SettingIngressTLSSecret    = "ingress.tlsSecret"
+	SettingRouteTLSTermination = "route.tlsTermination"
+)
+
+// The values of the 'expose' platform setting
+const (
+	ExposeIngress = "ingress"
+	ExposeRoute   = "route"
+)
+
+// IngressFilePrefix - the prefix of the files holding the generated Ingresses
+const IngressFilePrefix = "ingress-"
+
+// routeTerminations - the valid values of the 'route.tlsTermination' platform setting
+var routeTerminations = map[string]bool{"edge": true, "passthrough": true, "reencrypt": true}
+
+// ingressPath - a web app which is exposed by an Ingress. The paths which share a namespace, ingress class and host are
+// combined into a single Ingress.
+type ingressPath struct {
+	resourceID string
+	namespace  string
+	className  string
+	host       string
+	tlsSecret  string
+	path       string
+	service    string
+	port       int32
+}
+
+// ingressName - the Ingresses are named after their ingress class and host (e.g. nginx-shop.example.com)
+func (ip *ingressPath) ingressName() string {
+	name := "default"
+	if len(ip.host) > 0 {
+		name = objectName(strings.Replace(ip.host, "*", "wildcard", 1))
+	}
+	if len(ip.className) > 0 {
+		name = objectName(ip.className) + "-" + name
+	}
+	return name
+}
+
+// fileName - the file holding the Ingress of the path
+func (ip *ingressPath) fileName() string {
+	if len(ip.namespace) > 0 {
+		return IngressFilePrefix + ip.namespace + "-" + ip.ingressName() + ".yaml"
+	}
+	return IngressFilePrefix + ip.ingressName() + ".yaml"
+}
+
+// expose - how a web app is exposed outside of the cluster, or an empty string when it isn't
+func (bc *buildContext) expose() (string, error) {
+	expose := bc.platformParam(SettingExpose)
+	switch expose {
+	case "", ExposeIngress, ExposeRoute:
+		return expose, nil
+	}
+	return "", fmt.Errorf("%s: '%s' is not a valid value for '%s' (use %s or %s)", bc.resourceID(), expose, SettingExpose, ExposeIngress, ExposeRoute)
+}
+
+// hostAndPath - the host (the 'host' param, or the 'ingress.host' platform setting) and path of an exposed web app.
+// Either may be empty.
+func (bc *buildContext) hostAndPath() (string, string, error) {
+	host := bc.param("host")
+	if len(host) == 0 {
+		host = bc.platformParam(SettingIngressHost)
+	}
+	if len(host) > 0 && (len(host) > 253 || !dnsSubdomain.MatchString(strings.TrimPrefix(host, "*."))) {
+		return "", "", fmt.Errorf("%s: '%s' is not a valid host", bc.resourceID(), host)
+	}
+	path := bc.param("path")
+	if len(path) > 0 && !strings.HasPrefix(path, "/") {
+		return "", "", fmt.Errorf("%s: the path '%s' must start with '/'", bc.resourceID(), path)
+	}
+	return host, path, nil
+}
+
+// ingressPath - the path of a web app, which is added to the Ingress of its host
+func (bc *buildContext) ingressPath(service *Service) (*ingressPath, error) {
+	host, path, err := bc.hostAndPath()
+	if err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		path = "/"
+	}
+	return &ingressPath{
+		resourceID: bc.resourceID(),
+		namespace:  service.Metadata.Namespace,
+		className:  bc.platformParam(SettingIngressClassName),
+		host:       host,
+		tlsSecret:  bc.platformParam(SettingIngressTLSSecret),
+		path:       path,
+		service:    service.Metadata.Name,
+		port:       service.Spec.Ports[0].Port,
+	}, nil
+}
+
+// route - an OpenShift Route to the Service of a web app. OpenShift generates a host when one isn't given.
+func (bc *buildContext) route(service *Service) (*Route, error) {
+	host, path, err := bc.hostAndPath()
+	if err != nil {
+		return nil, err
+	}
+	route := &Route{
+		TypeMeta: TypeMeta{APIVersion: "route.openshift.io/v1", Kind: "Route"},
+		Metadata: bc.objectMeta(bc.provides.Name, true),
+		Spec: RouteSpec{
+			Host: host,
+			Path: path,
+			To:   RouteTargetReference{Kind: "Service", Name: service.Metadata.Name},
+			Port: &RoutePort{TargetPort: service.Spec.Ports[0].Port},
+		},
+	}
+	if termination := bc.platformParam(SettingRouteTLSTermination); len(termination) > 0 {
+		if !routeTerminations[termination] {
+			return nil, fmt.Errorf("%s: '%s' is not a valid value for '%s' (use edge, passthrough or reencrypt)", bc.resourceID(),
+				termination, SettingRouteTLSTermination)
+		}
+		if termination == "passthrough" && len(path) > 0 && path != "/" {
+			return nil, fmt.Errorf("%s: a route with passthrough TLS can't have a path (%s)", bc.resourceID(), path)
+		}
+		route.Spec.TLS = &TLSConfig{Termination: termination, InsecureEdgeTerminationPolicy: "Redirect"}
+	}
+	return route, nil
+}
+
+// buildIngresses combines the paths which share a namespace, ingress class and host into an Ingress, by file name. A
+// path can only be used once for each host, and a host can only have one TLS secret.
+func buildIngresses(paths []*ingressPath) (map[string]*Ingress, error) {
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].path != paths[j].path {
+			return paths[i].path < paths[j].path
+		}
+		return paths[i].resourceID < paths[j].resourceID
+	})
+	results := make(map[string]*Ingress)
+	used := make(map[string]*ingressPath)
+	for _, curPath := range paths {
+		fileName := curPath.fileName()
+		if previous := used[fileName+curPath.path]; previous != nil {
+			return nil, fmt.Errorf("%s and %s are both exposed at %s%s", previous.resourceID, curPath.resourceID, curPath.host, curPath.path)
+		}
+		used[fileName+curPath.path] = curPath
+		ingress := results[fileName]
+		if ingress == nil {
+			ingress = &Ingress{
+				TypeMeta: TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+				Metadata: ObjectMeta{Name: curPath.ingressName(), Namespace: curPath.namespace, Labels: map[string]string{LabelManagedBy: ManagedBy}},
+				Spec:     IngressSpec{IngressClassName: curPath.className, Rules: []IngressRule{{Host: curPath.host}}},
+			}
+			results[fileName] = ingress
+		}
+		if len(curPath.tlsSecret) > 0 {
+			if len(ingress.Spec.TLS) == 0 {
+				ingress.Spec.TLS = []IngressTLS{{SecretName: curPath.tlsSecret}}
+				if len(curPath.host) > 0 {
+					ingress.Spec.TLS[0].Hosts = []string{curPath.host}
+				}
+			} else if ingress.Spec.TLS[0].SecretName != curPath.tlsSecret {
+				return nil, fmt.Errorf("%s: the TLS secret '%s' differs from the one used by the other paths of %s ('%s')",
+					curPath.resourceID, curPath.tlsSecret, ingress.Metadata.Name, ingress.Spec.TLS[0].SecretName)
+			}
+		}
+		rule := &ingress.Spec.Rules[0]
+		rule.HTTP.Paths = append(rule.HTTP.Paths, HTTPIngressPath{Path: curPath.path, PathType: "Prefix",
+			Backend: IngressBackend{Service: IngressServiceBackend{Name: curPath.service, Port: ServiceBackendPort{Number: curPath.port}}}})
+	}
+	return results, nil
+}
@@ -0,0 +1,370 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kube generates Kubernetes files from resolved components
+package kube
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"rezolvr/drivers"
+	"rezolvr/model"
+	"rezolvr/templates"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Driver is the Kubernetes driver. It implements model.RezolvrDriver.
+type Driver struct{}
+
+// DriverName is the name used to select this driver, in an environment file's 'driver' field
+const DriverName = "kube"
+
+// DriverVersion is the version of this driver
+const DriverVersion = "0.0.1"
+
+// FormatManifest - the output format of this driver
+const FormatManifest = "kubernetes-manifest"
+
+// NamespaceFilePrefix - the prefix of the files holding the generated namespaces
+const NamespaceFilePrefix = "00-namespace-"
+
+// templateFiles holds the default templates, which are compiled into the driver
+//
+//go:embed templates/*.template
+var templateFiles embed.FS
+
+// builtInTemplates - the default templates, used when neither the project nor the user overrides them
+var builtInTemplates = drivers.MustSub(templateFiles, "templates")
+
+func init() {
+	drivers.Register(DriverName, func() model.RezolvrDriver {
+		return Driver{}
+	})
+	drivers.RegisterTemplates(DriverName, builtInTemplates)
+}
+
+// logger is supplied by rezolvr, via SetLogger
+var logger = model.GetLogger()
+
+// Name - the name used to select this driver
+func (rd Driver) Name() string {
+	return DriverName
+}
+
+// Version - the version of this driver
+func (rd Driver) Version() string {
+	return DriverVersion
+}
+
+// SetLogger - use rezolvr's logger, so that messages honour its log level and format
+func (rd Driver) SetLogger(newLogger model.Logger) {
+	logger = newLogger
+}
+
+// Capabilities - the resource types which are generated as typed objects, or have a built-in template, and the output
+// formats of this driver
+func (rd Driver) Capabilities() model.Capabilities {
+	capabilities := model.Capabilities{APIVersion: model.DriverAPIVersion, OutputFormats: []string{FormatManifest, FormatKustomization}}
+	capabilities.ResourceTypes = builderTypes()
+	builtIn, _ := fs.Glob(builtInTemplates, "*"+templates.Extension)
+	for _, curFileName := range builtIn {
+		name := strings.TrimSuffix(curFileName, templates.Extension)
+		if builders[name] == nil {
+			capabilities.ResourceTypes = append(capabilities.ResourceTypes, name)
+		}
+	}
+	sort.Strings(capabilities.ResourceTypes)
+	return capabilities
+}
+
+// Validate - check that the objects of every component can be generated (including that each storage claim they mount
+// is provided by a component, and that no two web apps are exposed at the same path), and that every template they need
+// can be parsed
+func (rd Driver) Validate(ctx context.Context, request *model.TransformRequest) error {
+	components := drivers.AllComponents(request)
+	provided := indexProvided(components)
+	overlay, err := overlayMetadata(request.PlatformSettings)
+	if err != nil {
+		return err
+	}
+	var ingressPaths []*ingressPath
+	for _, curComponent := range components {
+		for _, curProvides := range curComponent.Provides {
+			if drivers.SkipOutput(curProvides, request.PlatformSettings, logger) {
+				continue
+			}
+			bc := newBuildContext(curComponent, curProvides, request.PlatformSettings)
+			bc.provided = provided
+			bc.overlay = overlay
+			curTemplate, err := rd.loadTemplate(request.PluginDir, curProvides.Type)
+			if err == nil {
+				if _, err := template.New(curProvides.Type).Parse(curTemplate.contents); err != nil {
+					return fmt.Errorf("invalid template for %s: %v", curProvides.Type, err)
+				}
+			} else if build := builders[curProvides.Type]; build != nil {
+				if _, err := rd.buildObjects(build, bc, request.PluginDir); err != nil {
+					return err
+				}
+				if bc.ingress != nil {
+					ingressPaths = append(ingressPaths, bc.ingress)
+				}
+			}
+			// Missing templates are skipped when the output is generated
+		}
+	}
+	_, err = buildIngresses(ingressPaths)
+	return err
+}
+
+type providesTemplate struct {
+	name string
+	// resourceID - the ID of the provided resource (e.g. service.db.postgres:mydb)
+	resourceID string
+	Type       string
+	contents   string
+	// location - where the template was loaded from
+	location string
+	// namespace - the Namespace holding the generated objects, when it should be generated as well
+	namespace *Namespace
+	// ingress - the path of the resource within a shared Ingress, when it's exposed by one
+	ingress *ingressPath
+}
+
+func (rd Driver) loadTemplate(baseDir string, templateName string) (providesTemplate, error) {
+	templateType := strings.Split(templateName, ".")[0]
+	loaded, err := templates.NewLoader(DriverName, baseDir, builtInTemplates).Load(templateName)
+	if err != nil {
+		return providesTemplate{}, err
+	}
+	logger.Tracef("Using the %s template for %s: %s", loaded.Source, templateName, loaded.Location)
+	content := loaded.Content
+	curTemplate := providesTemplate{Type: templateType, contents: string(content), location: loaded.Location}
+	return curTemplate, nil
+}
+
+// newBuildContext reconciles the default and resource-specific platform settings of a provided resource
+func newBuildContext(c *model.Component, curProvides *model.Resource, platformSettings map[string]*model.Platform) *buildContext {
+	resolvedPlatformSettings := make(map[string]*model.Param)
+	if defaultPlatformSettings := platformSettings["default"]; defaultPlatformSettings != nil {
+		for k, v := range defaultPlatformSettings.Params {
+			resolvedPlatformSettings[k] = v
+		}
+	}
+	if resourcePlatformSettings := platformSettings[curProvides.Name]; resourcePlatformSettings != nil {
+		for k, v := range resourcePlatformSettings.Params {
+			resolvedPlatformSettings[k] = v
+		}
+	}
+	return &buildContext{component: c, provides: curProvides, platform: resolvedPlatformSettings}
+}
+
+func (rd Driver) populateTemplate(templateSource string, bc *buildContext) (string, error) {
+
+	// The following two variables are made available to the eval() method
+	data := map[string]interface{}{
+		"Platform":      bc.platform,
+		"Provides":      bc.provides,
+		"ProvideParams": bc.provides.Params,
+		"Uses":          bc.component.Uses,
+		"Component":     bc.component,
+	}
+
+	t, err := template.New(bc.provides.Type).Parse(templateSource)
+	if err != nil {
+		return "", fmt.Errorf("invalid Kubernetes template for %s: %v", bc.resourceID(), err)
+	}
+	buf := &bytes.Buffer{}
+	err = t.Execute(buf, data)
+	if err != nil {
+		return "", fmt.Errorf("error resolving the Kubernetes template for %s: %v", bc.resourceID(), err)
+	}
+	return buf.String(), nil
+}
+
+// buildObjects generates the typed objects for a provided resource, applies the resource type's patch template (if
+// there is one), and writes them as YAML
+func (rd Driver) buildObjects(build builder, bc *buildContext, pluginDir string) (string, error) {
+	if err := bc.resolveMetadata(); err != nil {
+		return "", err
+	}
+	objects, err := build(bc)
+	if err != nil {
+		return "", err
+	}
+	patchTemplate, err := rd.loadTemplate(pluginDir, bc.provides.Type+PatchSuffix)
+	if err == nil {
+		patch, err := rd.populateTemplate(patchTemplate.contents, bc)
+		if err != nil {
+			return "", err
+		}
+		if objects, err = applyPatches(objects, []byte(patch)); err != nil {
+			return "", fmt.Errorf("%s: %v", bc.resourceID(), err)
+		}
+	} else if !errors.Is(err, templates.ErrNotFound) {
+		return "", err
+	}
+	out, err := marshalObjects(objects)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", bc.resourceID(), err)
+	}
+	return string(out), nil
+}
+
+// transformProvidedResource generates the objects for each resource provided by a component. A template named after
+// the resource type replaces the generated objects entirely; resource types without a builder always use a template.
+func (rd Driver) transformProvidedResource(r *model.Component, pluginDir string, provided *providedResources, overlay *objectMetadata,
+	platformSettings map[string]*model.Platform) ([]providesTemplate, error) {
+	results := make([]providesTemplate, 0)
+	for _, curProvides := range r.Provides {
+		if drivers.SkipOutput(curProvides, platformSettings, logger) {
+			continue
+		}
+		bc := newBuildContext(r, curProvides, platformSettings)
+		bc.provided = provided
+		bc.overlay = overlay
+		template, err := rd.loadTemplate(pluginDir, curProvides.Type)
+		build := builders[curProvides.Type]
+		if err != nil && build != nil {
+			contents, err := rd.buildObjects(build, bc, pluginDir)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, providesTemplate{name: curProvides.Name, resourceID: drivers.ResourceID(curProvides),
+				Type: curProvides.Type, contents: contents, namespace: bc.namespaceObject(), ingress: bc.ingress})
+		} else if err != nil {
+			logger.Warnf("%v. Output will be skipped for: %v", err, curProvides.Type)
+		} else if len(template.contents) < 5 {
+			logger.Debugf("Empty template found. Output will be skipped for: %v", curProvides.Type)
+		} else {
+			if build != nil {
+				logger.Infof("The %s template (%s) replaces the generated objects of: %s", curProvides.Type, template.location,
+					curProvides.Name)
+			}
+			filledInTemplate, err := rd.populateTemplate(template.contents, bc)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, providesTemplate{name: curProvides.Name, resourceID: drivers.ResourceID(curProvides),
+				Type: template.Type, contents: filledInTemplate})
+		}
+	}
+	return results, nil
+}
+
+// Transform generates the output for every component in the state, along with the updated components
+func (rd Driver) Transform(ctx context.Context, request *model.TransformRequest) (*model.TransformResult, error) {
+	result := &model.TransformResult{Artifacts: []model.Artifact{}}
+	if request.UpdatedComponents == nil {
+		logger.Infof("No components / resources to transform")
+		return result, nil
+	}
+
+	// For now, all components / resources should be regenerated. Each file is named after the resource (or Ingress) it
+	// holds, so two of them can't share a name.
+	allServices := make(map[string]string)
+	owners := make(map[string]string)
+	addFile := func(name string, owner string, contents string) error {
+		if previous, ok := owners[name]; ok {
+			if previous > owner {
+				previous, owner = owner, previous
+			}
+			return fmt.Errorf("%s and %s would both be written to %s.yaml (give them different names)", previous, owner, name)
+		}
+		owners[name] = owner
+		allServices[name] = contents
+		return nil
+	}
+	namespaces := make(map[string]*Namespace)
+	var ingressPaths []*ingressPath
+
+	components := drivers.AllComponents(request)
+	provided := indexProvided(components)
+	overlay, err := overlayMetadata(request.PlatformSettings)
+	if err != nil {
+		return nil, err
+	}
+	for _, curComponent := range components {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		transformed, err := rd.transformProvidedResource(curComponent, request.PluginDir, provided, overlay, request.PlatformSettings)
+		if err != nil {
+			return nil, err
+		}
+		for _, curProvides := range transformed {
+			if err := addFile(curProvides.name, curProvides.resourceID, curProvides.contents); err != nil {
+				return nil, err
+			}
+			if curProvides.namespace != nil {
+				namespaces[curProvides.namespace.Metadata.Name] = curProvides.namespace
+			}
+			if curProvides.ingress != nil {
+				ingressPaths = append(ingressPaths, curProvides.ingress)
+			}
+		}
+	}
+	// Each namespace is written to its own file, which sorts ahead of the others so that 'kubectl apply -f' creates it
+	// before the objects within it
+	namespaceNames := make([]string, 0, len(namespaces))
+	for k := range namespaces {
+		namespaceNames = append(namespaceNames, k)
+	}
+	sort.Strings(namespaceNames)
+	for _, k := range namespaceNames {
+		content, err := marshalObjects([]interface{}{namespaces[k]})
+		if err != nil {
+			return nil, err
+		}
+		result.Artifacts = append(result.Artifacts, model.Artifact{Path: NamespaceFilePrefix + k + ".yaml", Format: FormatManifest, Content: content})
+	}
+	// The web apps which are exposed by the same Ingress are combined, so each Ingress has its own file
+	ingresses, err := buildIngresses(ingressPaths)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range ingresses {
+		content, err := marshalObjects([]interface{}{v})
+		if err != nil {
+			return nil, err
+		}
+		if err := addFile(strings.TrimSuffix(k, ".yaml"), "the Ingress "+v.Metadata.Name, string(content)); err != nil {
+			return nil, err
+		}
+	}
+	// Return a file for each service to rezolvr, which writes them
+	if len(allServices) > 0 {
+		names := make([]string, 0, len(allServices))
+		for k := range allServices {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			result.Artifacts = append(result.Artifacts, model.Artifact{Path: k + ".yaml", Format: FormatManifest, Content: []byte(allServices[k])})
+		}
+	} else {
+		logger.Infof("No services were generated. Skipping the generation of Kubernetes files...")
+	}
+	if overlay != nil {
+		if result.Artifacts, err = kustomizeLayout(request.Environment, overlay, result.Artifacts); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,747 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"rezolvr/drivers"
+	"rezolvr/drivers/drivertest"
+	"rezolvr/model"
+	"rezolvr/utils"
+	"rezolvr/validation"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func postgresComponent() *model.Component {
+	return &model.Component{
+		Name: "postgres",
+		Type: "resource.db.postgres",
+		Provides: map[string]*model.Resource{
+			"service.db.postgres:mydb": {Name: "mydb", Type: "service.db.postgres",
+				Params: drivertest.Params("imageName", "registry/postgres", "db_port", "5432")},
+		},
+		Uses: map[string]*model.Resource{
+			"environment": {Type: "environment", Params: drivertest.Params("POSTGRES_USER", "admin", "POSTGRES_DB", "catalog")},
+			"dbsecret":    {Name: "dbsecret", Type: "secret", Params: drivertest.Params("POSTGRES_PASSWORD", "password")},
+			"storage":     {Type: "storage", Params: drivertest.Params("volumeName", "dbvolume", "volumeClaimName", "dbclaim", "mountPath", "/data")},
+		},
+	}
+}
+
+func volumeComponent(claimNames ...string) *model.Component {
+	component := &model.Component{Name: "volumes", Type: "resource.storage.volume", Provides: map[string]*model.Resource{}}
+	for _, curName := range claimNames {
+		component.Provides["storage.volume-claim:"+curName] = &model.Resource{Name: curName, Type: "storage.volume-claim",
+			Params: drivertest.Params("name", curName, "volumeRequestSize", "1Gi")}
+	}
+	return component
+}
+
+func Test_Transform(t *testing.T) {
+	request := &model.TransformRequest{
+		UpdatedComponents: map[string]*model.Component{"resource.db.postgres:postgres": postgresComponent()},
+		State:             &model.State{Components: map[string]*model.Component{"resource.storage.volume:volumes": volumeComponent("dbclaim")}},
+		PlatformSettings: map[string]*model.Platform{
+			"default": {Params: drivertest.Params("numInstances", "2", "serviceType", "NodePort", "namespace", "shop", "labels.team", "payments")},
+			"mydb":    {Params: drivertest.Params("numInstances", "1", "serviceType", "ClusterIP", "annotations.example.com/owner", "db-team")},
+		},
+	}
+	result, err := Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(result.Artifacts))
+	assert.Equal(t, "00-namespace-shop.yaml", result.Artifacts[0].Path)
+	assert.Equal(t, "dbclaim.yaml", result.Artifacts[1].Path)
+	assert.Equal(t, "mydb.yaml", result.Artifacts[2].Path)
+	assert.Equal(t, FormatManifest, result.Artifacts[2].Format)
+	assert.Equal(t, `apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop
+  labels:
+    app.kubernetes.io/managed-by: rezolvr
+`, string(result.Artifacts[0].Content))
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: mydb
+  namespace: shop
+  labels:
+    app.kubernetes.io/component: db.postgres
+    app.kubernetes.io/instance: mydb
+    app.kubernetes.io/managed-by: rezolvr
+    app.kubernetes.io/name: postgres
+    rezolvr.io/component: resource.db.postgres-postgres
+    team: payments
+  annotations:
+    example.com/owner: db-team
+    rezolvr.io/component: resource.db.postgres:postgres
+spec:
+  selector:
+    matchLabels:
+      app: mydb
+  replicas: 1
+  template:
+    metadata:
+      labels:
+        app: mydb
+        app.kubernetes.io/component: db.postgres
+        app.kubernetes.io/instance: mydb
+        app.kubernetes.io/managed-by: rezolvr
+        app.kubernetes.io/name: postgres
+        rezolvr.io/component: resource.db.postgres-postgres
+        team: payments
+    spec:
+      containers:
+      - name: mydb
+        image: registry/postgres
+        volumeMounts:
+        - mountPath: /data
+          name: dbvolume
+        ports:
+        - containerPort: 5432
+        env:
+        - name: POSTGRES_PASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: dbsecret
+              key: password
+        - name: POSTGRES_DB
+          value: catalog
+        - name: POSTGRES_USER
+          value: admin
+      volumes:
+      - name: dbvolume
+        persistentVolumeClaim:
+          claimName: dbclaim
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: mydb-service
+  namespace: shop
+  labels:
+    app: mydb
+    app.kubernetes.io/component: db.postgres
+    app.kubernetes.io/instance: mydb
+    app.kubernetes.io/managed-by: rezolvr
+    app.kubernetes.io/name: postgres
+    rezolvr.io/component: resource.db.postgres-postgres
+    team: payments
+  annotations:
+    example.com/owner: db-team
+    rezolvr.io/component: resource.db.postgres:postgres
+spec:
+  type: ClusterIP
+  selector:
+    app: mydb
+  ports:
+  - protocol: TCP
+    port: 5432
+    targetPort: 5432
+`, string(result.Artifacts[2].Content))
+
+	// The namespace isn't generated when it's opted out
+	request.PlatformSettings["default"].Params["createNamespace"] = &model.Param{Name: "createNamespace", Value: "false"}
+	result, err = Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result.Artifacts))
+}
+
+func Test_Metadata(t *testing.T) {
+	component := postgresComponent()
+	platform := map[string]*model.Platform{"default": {Params: drivertest.Params("namespace", "Shop")}}
+	bc := newBuildContext(component, component.Provides["service.db.postgres:mydb"], platform)
+	assert.EqualError(t, bc.resolveMetadata(), "service.db.postgres:mydb: 'Shop' is not a valid namespace (use lower case letters, digits and '-')")
+
+	platform["default"] = &model.Platform{Params: drivertest.Params("labels.team/", "payments")}
+	bc = newBuildContext(component, component.Provides["service.db.postgres:mydb"], platform)
+	assert.EqualError(t, bc.resolveMetadata(), "service.db.postgres:mydb: 'team/' is not a valid label")
+
+	platform["default"] = &model.Platform{Params: drivertest.Params("labels.team", "pay ments")}
+	bc = newBuildContext(component, component.Provides["service.db.postgres:mydb"], platform)
+	assert.EqualError(t, bc.resolveMetadata(), "service.db.postgres:mydb: 'pay ments' is not a valid value for the 'team' label")
+
+	// The standard labels can't be overridden
+	platform["default"] = &model.Platform{Params: drivertest.Params("labels.app.kubernetes.io/managed-by", "helm", "namespace", "default")}
+	bc = newBuildContext(component, component.Provides["service.db.postgres:mydb"], platform)
+	assert.Nil(t, bc.resolveMetadata())
+	assert.Equal(t, ManagedBy, bc.objectMeta("postgres", true).Labels[LabelManagedBy])
+	assert.Equal(t, "default", bc.objectMeta("postgres", true).Namespace)
+	assert.Equal(t, "", bc.objectMeta("postgres", false).Namespace)
+	assert.Nil(t, bc.namespaceObject())
+
+	assert.Equal(t, "resource.web.app-welcome", labelValue("resource.web.app:welcome"))
+}
+
+func Test_Volumes(t *testing.T) {
+	component := postgresComponent()
+	component.Uses["storage"] = &model.Resource{Type: "storage", Params: drivertest.Params("volumeName", "data", "mountPath", "/data")}
+	component.Uses["config"] = &model.Resource{Name: "config", Type: "storage",
+		Params: drivertest.Params("volumeClaimName", "configclaim", "mountPath", "/etc/app", "readOnly", "true", "subPath", "app")}
+	component.Uses["logs"] = &model.Resource{Name: "logs", Type: "storage",
+		Params: drivertest.Params("volumeClaimName", "configclaim", "mountPath", "/var/log/app", "subPath", "logs")}
+	bc := newBuildContext(component, component.Provides["service.db.postgres:mydb"], nil)
+	bc.provided = indexProvided(map[string]*model.Component{"volumes": volumeComponent("dataclaim", "configclaim")})
+
+	volumeMounts, volumes, err := bc.volumes()
+	assert.Nil(t, err)
+	assert.Equal(t, []VolumeMount{
+		{MountPath: "/etc/app", Name: "configclaim", ReadOnly: true, SubPath: "app"},
+		{MountPath: "/var/log/app", Name: "configclaim", SubPath: "logs"},
+		{MountPath: "/data", Name: "data"},
+	}, volumeMounts)
+	assert.Equal(t, []Volume{
+		{Name: "configclaim", PersistentVolumeClaim: &ClaimVolumeSource{ClaimName: "configclaim"}},
+		{Name: "data", PersistentVolumeClaim: &ClaimVolumeSource{ClaimName: "dataclaim"}},
+	}, volumes)
+
+	// Every claim must be provided by a component
+	bc.provided = indexProvided(map[string]*model.Component{"volumes": volumeComponent("configclaim")})
+	_, _, err = bc.volumes()
+	assert.EqualError(t, err, "service.db.postgres:mydb: the claim 'dataclaim' isn't provided by any component (no storage.volume-claim is named 'dataclaim')")
+
+	component.Uses["logs"].Params["readOnly"] = &model.Param{Name: "readOnly", Value: "maybe"}
+	_, _, err = bc.volumes()
+	assert.EqualError(t, err, "service.db.postgres:mydb: 'maybe' is not a valid value for 'readOnly' (use true or false)")
+
+	component.Uses["logs"].Params = drivertest.Params("volumeClaimName", "configclaim")
+	_, _, err = bc.volumes()
+	assert.EqualError(t, err, "service.db.postgres:mydb: the storage used by postgres doesn't have a 'mountPath'")
+}
+
+func Test_TransformSkipsExternalResources(t *testing.T) {
+	request := &model.TransformRequest{
+		UpdatedComponents: map[string]*model.Component{"resource.db.postgres:postgres": postgresComponent()},
+		PlatformSettings:  map[string]*model.Platform{"mydb": {Params: drivertest.Params("isExternal", "true")}},
+	}
+	result, err := Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(result.Artifacts))
+}
+
+func Test_SeveralWorkloads(t *testing.T) {
+	// Each workload provided by a component is named after its resource, so they don't conflict
+	component := postgresComponent()
+	component.Provides["service.db.postgres:replica"] = &model.Resource{Name: "replica", Type: "service.db.postgres",
+		Params: drivertest.Params("imageName", "registry/postgres", "db_port", "5433")}
+	request := &model.TransformRequest{
+		UpdatedComponents: map[string]*model.Component{"resource.db.postgres:postgres": component},
+		State:             &model.State{Components: map[string]*model.Component{"resource.storage.volume:volumes": volumeComponent("dbclaim")}},
+	}
+	result, err := Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	names := make(map[string]bool)
+	for _, curArtifact := range result.Artifacts {
+		docs, err := DecodeDocuments(curArtifact.Content)
+		assert.Nil(t, err)
+		for _, curDoc := range docs {
+			kind, name := objectKey(curDoc)
+			names[kind+"/"+name] = true
+		}
+	}
+	assert.True(t, names["Deployment/mydb"])
+	assert.True(t, names["Deployment/replica"])
+	assert.True(t, names["Service/mydb-service"])
+	assert.True(t, names["Service/replica-service"])
+
+	// Resources with the same name would be written to the same file
+	request.State.Components["resource.storage.volume:volumes"] = volumeComponent("dbclaim", "mydb")
+	_, err = Driver{}.Transform(context.Background(), request)
+	assert.EqualError(t, err, "service.db.postgres:mydb and storage.volume-claim:mydb would both be written to mydb.yaml (give them different names)")
+}
+
+func Test_BuildErrors(t *testing.T) {
+	component := postgresComponent()
+	component.Provides["service.db.postgres:mydb"].Params = drivertest.Params("db_port", "5432")
+	request := &model.TransformRequest{UpdatedComponents: map[string]*model.Component{"resource.db.postgres:postgres": component,
+		"resource.storage.volume:volumes": volumeComponent("dbclaim")}}
+	err := Driver{}.Validate(context.Background(), request)
+	assert.EqualError(t, err, "service.db.postgres:mydb: missing a value for the 'imageName' param")
+
+	component.Provides["service.db.postgres:mydb"].Params = drivertest.Params("imageName", "postgres", "db_port", "five")
+	_, err = Driver{}.Transform(context.Background(), request)
+	assert.EqualError(t, err, "service.db.postgres:mydb: 'five' is not a valid number for 'db_port'")
+}
+
+func Test_PersistentVolumes(t *testing.T) {
+	bc := &buildContext{component: &model.Component{Name: "volumes"}, provides: &model.Resource{Name: "data", Type: "storage.volume-claim",
+		Params: drivertest.Params("name", "dataclaim", "accessModes", "ReadWriteOnce", "volumeRequestSize", "1Gi")}}
+	objects, err := buildPersistentVolumeClaim(bc)
+	assert.Nil(t, err)
+	out, err := marshalObjects(objects)
+	assert.Nil(t, err)
+	assert.Equal(t, `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: dataclaim
+spec:
+  accessModes:
+  - ReadWriteOnce
+  resources:
+    requests:
+      storage: 1Gi
+`, string(out))
+}
+
+func Test_ApplyPatches(t *testing.T) {
+	objects := []interface{}{
+		&Service{TypeMeta: TypeMeta{APIVersion: "v1", Kind: "Service"},
+			Metadata: ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:     ServiceSpec{Type: "NodePort", Selector: map[string]string{"app": "web"}, Ports: []ServicePort{{Port: 80}}}},
+	}
+
+	patched, err := applyPatches(objects, []byte("kind: Service\nmetadata:\n  namespace: null\n  annotations:\n    team: web\nspec:\n  type: LoadBalancer\n"))
+	assert.Nil(t, err)
+	out, err := marshalObjects(patched)
+	assert.Nil(t, err)
+	assert.Equal(t, `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  annotations:
+    team: web
+spec:
+  type: LoadBalancer
+  selector:
+    app: web
+  ports:
+  - port: 80
+`, string(out))
+
+	_, err = applyPatches(objects, []byte("kind: Service\nmetadata:\n  name: other\n"))
+	assert.EqualError(t, err, "invalid patch: no Service named 'other' was generated")
+	_, err = applyPatches(objects, []byte("metadata:\n  name: web\n"))
+	assert.EqualError(t, err, "invalid patch: each document must have a 'kind'")
+
+	// An empty patch leaves the objects alone
+	unchanged, err := applyPatches(objects, []byte("\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, objects, unchanged)
+}
+
+func Test_Capabilities(t *testing.T) {
+	capabilities := Driver{}.Capabilities()
+	assert.Equal(t, model.DriverAPIVersion, capabilities.APIVersion)
+	for _, curType := range []string{"service", "service.web.app", "service.db.postgres", "storage.volume", "storage.volume-claim", "environment.secret"} {
+		assert.Contains(t, capabilities.ResourceTypes, curType)
+	}
+}
+
+func Test_Environment(t *testing.T) {
+	environment := &model.Component{Name: "env", Provides: map[string]*model.Resource{
+		"environment.secret:dbCredentials": {Name: "dbCredentials", Type: "environment.secret",
+			Params: drivertest.Params("db_password", "passwordie", "db_username", "admin")},
+		"environment.properties:dbEnvProps": {Name: "dbEnvProps", Type: "environment.properties",
+			Params: drivertest.Params("db_name", "catalog", "db_port", "5432")},
+	}}
+
+	objects, err := buildSecret(newBuildContext(environment, environment.Provides["environment.secret:dbCredentials"], nil))
+	assert.Nil(t, err)
+	out, err := marshalObjects(objects)
+	assert.Nil(t, err)
+	assert.Equal(t, `apiVersion: v1
+kind: Secret
+metadata:
+  name: dbcredentials
+type: Opaque
+data:
+  db_password: cGFzc3dvcmRpZQ==
+  db_username: YWRtaW4=
+`, string(out))
+
+	objects, err = buildConfigMap(newBuildContext(environment, environment.Provides["environment.properties:dbEnvProps"], nil))
+	assert.Nil(t, err)
+	out, err = marshalObjects(objects)
+	assert.Nil(t, err)
+	assert.Equal(t, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: dbenvprops
+data:
+  db_name: catalog
+  db_port: "5432"
+`, string(out))
+
+	component := postgresComponent()
+	component.Uses = map[string]*model.Resource{
+		"environment": {Type: "environment", Params: map[string]*model.Param{
+			"POSTGRES_DB": {Name: "POSTGRES_DB", Value: "catalog",
+				Formula: `{{with(index .Needs "environment.properties:dbEnvProps")}}{{.Params.db_name.Value}}{{end}}`},
+			"POSTGRES_PASSWORD": {Name: "POSTGRES_PASSWORD", Value: "passwordie",
+				Formula: `{{with(index .Needs "environment.secret:dbCredentials")}}{{.Params.db_password.Value}}{{end}}`},
+			"PGDATA": {Name: "PGDATA", Value: "/data/pg"},
+		}},
+		"environment.properties:dbEnvProps": {Name: "dbEnvProps", Type: "environment.properties"},
+		"environment.secret:dbCredentials": {Name: "dbCredentials", Type: "environment.secret",
+			Params: drivertest.Params("POSTGRES_USER", "db_username")},
+	}
+	bc := newBuildContext(component, component.Provides["service.db.postgres:mydb"], nil)
+	bc.provided = indexProvided(map[string]*model.Component{"env": environment})
+	env, envFrom, err := bc.env()
+	assert.Nil(t, err)
+	assert.Equal(t, []EnvVar{
+		{Name: "PGDATA", Value: "/data/pg"},
+		{Name: "POSTGRES_DB", ValueFrom: &EnvVarSource{ConfigMapKeyRef: &KeySelector{Name: "dbenvprops", Key: "db_name"}}},
+		{Name: "POSTGRES_PASSWORD", ValueFrom: &EnvVarSource{SecretKeyRef: &KeySelector{Name: "dbcredentials", Key: "db_password"}}},
+		{Name: "POSTGRES_USER", ValueFrom: &EnvVarSource{SecretKeyRef: &KeySelector{Name: "dbcredentials", Key: "db_username"}}},
+	}, env)
+	assert.Equal(t, []EnvFromSource{{ConfigMapRef: &LocalObjectReference{Name: "dbenvprops"}}}, envFrom)
+
+	// Values from resources which aren't generated are inlined
+	bc.provided = indexProvided(nil)
+	env, _, err = bc.env()
+	assert.Nil(t, err)
+	assert.Equal(t, EnvVar{Name: "POSTGRES_PASSWORD", Value: "passwordie"}, env[2])
+
+	environment.Provides["environment.secret:dbCredentials"].Params["db password"] = &model.Param{Name: "db password"}
+	_, err = buildSecret(newBuildContext(environment, environment.Provides["environment.secret:dbCredentials"], nil))
+	assert.EqualError(t, err, "environment.secret:dbCredentials: 'db password' is not a valid key (use letters, digits, '-', '_' or '.')")
+}
+
+// exampleRequest - the request which 'rezolvr apply' sends to the driver for the components of an example (its
+// rezolvr-*.yaml files), resolved within an empty state along with the environment file
+func exampleRequest(t *testing.T, name string, envFile string) *model.TransformRequest {
+	dir := filepath.Join("..", "..", "examples", name)
+	componentFiles, err := filepath.Glob(filepath.Join(dir, "rezolvr-*.yaml"))
+	assert.Nil(t, err)
+	components := make(map[string]*model.Component)
+	for _, curFile := range componentFiles {
+		for _, curComponent := range loadExampleComponents(t, curFile) {
+			components[curComponent.ID()] = curComponent
+		}
+	}
+	environments := loadExampleComponents(t, filepath.Join(dir, envFile))
+	if !assert.Len(t, environments, 1) {
+		t.FailNow()
+	}
+	environment := environments[0]
+
+	state, err := model.LoadState(nil)
+	assert.Nil(t, err)
+	validation.MergeEnvironmentProperties(state, environment.Provides)
+	resolved, err := utils.ResolveAllComponents(state, validation.GetImpactedComponents(state, components))
+	assert.Nil(t, err)
+	platformSettings := make(map[string]*model.Platform)
+	for _, curUses := range environment.Uses {
+		if curUses.Type == "platform.settings" {
+			platformSettings[curUses.Name] = &model.Platform{Params: curUses.Params}
+		}
+	}
+	return &model.TransformRequest{UpdatedComponents: resolved, State: state, PlatformSettings: platformSettings,
+		Environment: environment.Name}
+}
+
+func loadExampleComponents(t *testing.T, fileName string) []*model.Component {
+	content, err := ioutil.ReadFile(fileName)
+	assert.Nil(t, err)
+	components, err := model.LoadComponents(content)
+	assert.Nil(t, err, fileName)
+	return components
+}
+
+func Test_EnvironmentReferences(t *testing.T) {
+	// In the volume example, the catalog copies the database's password from its provides section, which copies it from
+	// the dbEnvProps environment properties. The Deployment refers to the ConfigMap, rather than repeating the value.
+	request := exampleRequest(t, "volume", "env-dev-kube.yaml")
+	result, err := Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	for _, curArtifact := range result.Artifacts {
+		docs, err := DecodeDocuments(curArtifact.Content)
+		assert.Nil(t, err)
+		for _, curDoc := range docs {
+			if kind, _ := objectKey(curDoc); kind == "Deployment" {
+				assert.NotContains(t, string(curArtifact.Content), "passwordie", curArtifact.Path)
+			}
+		}
+	}
+	component := request.UpdatedComponents["resource.web.app:catalog"]
+	bc := newBuildContext(component, component.Provides["service.web.app:catalogapp"], request.PlatformSettings)
+	bc.provided = indexProvided(drivers.AllComponents(request))
+	env, _, err := bc.env()
+	assert.Nil(t, err)
+	assert.Contains(t, env, EnvVar{Name: "DB_PW", ValueFrom: &EnvVarSource{ConfigMapKeyRef: &KeySelector{Name: "dbenvprops", Key: "db_password"}}})
+	assert.Contains(t, env, EnvVar{Name: "DB_HOST", ValueFrom: &EnvVarSource{ConfigMapKeyRef: &KeySelector{Name: "dbenvprops", Key: "db_host"}}})
+
+	// A cycle of formulas ends, and the value is inlined
+	bc.provided.resources["service.db.postgres:mydb"].Params["db_password"].Formula =
+		`{{with(index .Needs "service.db.postgres:mydb")}}{{.Params.db_password.Value}}{{end}}`
+	env, _, err = bc.env()
+	assert.Nil(t, err)
+	assert.Contains(t, env, EnvVar{Name: "DB_PW", Value: "passwordie"})
+}
+
+func Test_DeploymentHints(t *testing.T) {
+	component := postgresComponent()
+	component.Uses = nil
+	component.DeploymentHints = &model.DeploymentHints{Instances: 3, AvailabilityZones: 2, MinRAM: "4GB", MaxRAM: "8Gi", MinCPU: "500m",
+		MinAvailable: 2, MaxInstances: 6, HealthCheck: &model.HealthCheck{Path: "/health", InitialDelaySeconds: 10}}
+	bc := newBuildContext(component, component.Provides["service.db.postgres:mydb"], nil)
+	objects, err := buildPostgres(bc)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(objects))
+
+	// The autoscaler sets the number of replicas
+	deployment := objects[0].(*Deployment)
+	assert.Nil(t, deployment.Spec.Replicas)
+	container := deployment.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, ResourceRequirements{Requests: map[string]string{"memory": "4Gi", "cpu": "500m"}, Limits: map[string]string{"memory": "8Gi"}},
+		container.Resources)
+	assert.Equal(t, &Probe{HTTPGet: &HTTPGetAction{Path: "/health", Port: 5432}, InitialDelaySeconds: 10}, container.ReadinessProbe)
+	assert.Equal(t, container.ReadinessProbe, container.LivenessProbe)
+	assert.Equal(t, []TopologySpreadConstraint{{MaxSkew: 1, TopologyKey: ZoneTopologyKey, WhenUnsatisfiable: "ScheduleAnyway",
+		LabelSelector: LabelSelector{MatchLabels: map[string]string{"app": "mydb"}}}}, deployment.Spec.Template.Spec.TopologySpreadConstraints)
+
+	budget := objects[2].(*PodDisruptionBudget)
+	assert.Equal(t, int32(2), *budget.Spec.MinAvailable)
+	assert.Nil(t, budget.Spec.MaxUnavailable)
+
+	autoscaler := objects[3].(*HorizontalPodAutoscaler)
+	assert.Equal(t, "mydb", autoscaler.Spec.ScaleTargetRef.Name)
+	assert.Equal(t, int32(3), *autoscaler.Spec.MinReplicas)
+	assert.Equal(t, int32(6), autoscaler.Spec.MaxReplicas)
+	assert.Equal(t, int32(DefaultTargetCPUUtilization), *autoscaler.Spec.Metrics[0].Resource.Target.AverageUtilization)
+
+	// The platform settings take precedence over the hints. A disruption budget is only generated for minAvailable.
+	component.DeploymentHints = &model.DeploymentHints{Instances: 3, HealthCheck: &model.HealthCheck{}}
+	bc = newBuildContext(component, component.Provides["service.db.postgres:mydb"], map[string]*model.Platform{
+		"default": {Params: drivertest.Params("numInstances", "1")}})
+	objects, err = buildPostgres(bc)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(objects))
+	deployment = objects[0].(*Deployment)
+	assert.Equal(t, int32(1), *deployment.Spec.Replicas)
+	assert.Equal(t, &Probe{TCPSocket: &TCPSocketAction{Port: 5432}}, deployment.Spec.Template.Spec.Containers[0].LivenessProbe)
+
+	component.DeploymentHints = &model.DeploymentHints{Instances: 3}
+	objects, err = buildPostgres(newBuildContext(component, component.Provides["service.db.postgres:mydb"], nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(objects))
+	assert.Equal(t, int32(3), *objects[0].(*Deployment).Spec.Replicas)
+
+	component.DeploymentHints = &model.DeploymentHints{Instances: 2, MinAvailable: 2}
+	_, err = buildPostgres(newBuildContext(component, component.Provides["service.db.postgres:mydb"], nil))
+	assert.EqualError(t, err, "service.db.postgres:mydb: the 'minAvailable' deployment hint of postgres (2) must be less than the number of instances (2)")
+
+	component.DeploymentHints = &model.DeploymentHints{MinRAM: "lots"}
+	_, err = buildPostgres(newBuildContext(component, component.Provides["service.db.postgres:mydb"], nil))
+	assert.EqualError(t, err, "service.db.postgres:mydb: 'lots' is not a valid value for the 'minRam' deployment hint of postgres (e.g. 512Mi, 4GB or 500m)")
+
+	component.DeploymentHints = &model.DeploymentHints{MinInstances: 4, MaxInstances: 2}
+	_, err = buildPostgres(newBuildContext(component, component.Provides["service.db.postgres:mydb"], nil))
+	assert.EqualError(t, err, "service.db.postgres:mydb: the 'maxInstances' deployment hint of postgres (2) is less than the minimum number of instances (4)")
+
+	component.DeploymentHints = &model.DeploymentHints{Instances: -1}
+	_, err = buildPostgres(newBuildContext(component, component.Provides["service.db.postgres:mydb"], nil))
+	assert.EqualError(t, err, "service.db.postgres:mydb: the 'instances' deployment hint of postgres can't be negative")
+}
+
+func webAppComponent(name string, path string) *model.Component {
+	return &model.Component{
+		Name: name,
+		Type: "resource.web.app",
+		Provides: map[string]*model.Resource{
+			"service.web.app:" + name + "app": {Name: name + "app", Type: "service.web.app",
+				Params: drivertest.Params("imageName", "registry/"+name, "port", "3000", "path", path)},
+		},
+	}
+}
+
+func Test_Ingress(t *testing.T) {
+	request := &model.TransformRequest{
+		UpdatedComponents: map[string]*model.Component{
+			"resource.web.app:catalog": webAppComponent("catalog", "/charters"),
+			"resource.web.app:welcome": webAppComponent("welcome", "/message"),
+		},
+		PlatformSettings: map[string]*model.Platform{
+			"default": {Params: drivertest.Params("expose", "ingress", "ingress.host", "shop.example.com", "ingress.className", "nginx",
+				"ingress.tlsSecret", "shop-tls")},
+		},
+	}
+	result, err := Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(result.Artifacts))
+	assert.Equal(t, "catalogapp.yaml", result.Artifacts[0].Path)
+	assert.Contains(t, string(result.Artifacts[0].Content), "type: ClusterIP")
+	assert.Equal(t, "ingress-nginx-shop.example.com.yaml", result.Artifacts[1].Path)
+	assert.Equal(t, `apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: nginx-shop.example.com
+  labels:
+    app.kubernetes.io/managed-by: rezolvr
+spec:
+  ingressClassName: nginx
+  tls:
+  - hosts:
+    - shop.example.com
+    secretName: shop-tls
+  rules:
+  - host: shop.example.com
+    http:
+      paths:
+      - path: /charters
+        pathType: Prefix
+        backend:
+          service:
+            name: catalogapp-service
+            port:
+              number: 3000
+      - path: /message
+        pathType: Prefix
+        backend:
+          service:
+            name: welcomeapp-service
+            port:
+              number: 3000
+`, string(result.Artifacts[1].Content))
+
+	// A web app with another host has its own Ingress
+	request.PlatformSettings["welcomeapp"] = &model.Platform{Params: drivertest.Params("ingress.host", "welcome.example.com")}
+	result, err = Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(result.Artifacts))
+	assert.Equal(t, "ingress-nginx-welcome.example.com.yaml", result.Artifacts[2].Path)
+
+	// Two web apps can't share a path
+	request.UpdatedComponents["resource.web.app:welcome"] = webAppComponent("welcome", "/charters")
+	delete(request.PlatformSettings, "welcomeapp")
+	err = Driver{}.Validate(context.Background(), request)
+	assert.EqualError(t, err, "service.web.app:catalogapp and service.web.app:welcomeapp are both exposed at shop.example.com/charters")
+	_, err = Driver{}.Transform(context.Background(), request)
+	assert.EqualError(t, err, "service.web.app:catalogapp and service.web.app:welcomeapp are both exposed at shop.example.com/charters")
+}
+
+func Test_Route(t *testing.T) {
+	component := webAppComponent("catalog", "/charters")
+	platform := map[string]*model.Platform{"default": {Params: drivertest.Params("expose", "route", "route.tlsTermination", "edge")}}
+	objects, err := buildWebApp(newBuildContext(component, component.Provides["service.web.app:catalogapp"], platform))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(objects))
+	assert.Equal(t, RouteSpec{Path: "/charters", To: RouteTargetReference{Kind: "Service", Name: "catalogapp-service"},
+		Port: &RoutePort{TargetPort: 3000}, TLS: &TLSConfig{Termination: "edge", InsecureEdgeTerminationPolicy: "Redirect"}},
+		objects[2].(*Route).Spec)
+
+	platform["default"].Params["route.tlsTermination"].Value = "passthrough"
+	_, err = buildWebApp(newBuildContext(component, component.Provides["service.web.app:catalogapp"], platform))
+	assert.EqualError(t, err, "service.web.app:catalogapp: a route with passthrough TLS can't have a path (/charters)")
+
+	platform["default"].Params["expose"].Value = "loadbalancer"
+	_, err = buildWebApp(newBuildContext(component, component.Provides["service.web.app:catalogapp"], platform))
+	assert.EqualError(t, err, "service.web.app:catalogapp: 'loadbalancer' is not a valid value for 'expose' (use ingress or route)")
+}
+
+func Test_KustomizeLayout(t *testing.T) {
+	request := &model.TransformRequest{
+		UpdatedComponents: map[string]*model.Component{"resource.db.postgres:postgres": postgresComponent()},
+		State: &model.State{Components: map[string]*model.Component{
+			"resource.storage.volume:volumes": volumeComponent("dbclaim"),
+			"environment.properties": {Name: "environment", Provides: map[string]*model.Resource{
+				"environment.properties:dbEnvProps": {Name: "dbEnvProps", Type: "environment.properties", Params: drivertest.Params("db_name", "catalog")},
+			}},
+		}},
+		PlatformSettings: map[string]*model.Platform{
+			"default": {Params: drivertest.Params("layout", "kustomize", "namespace", "shop", "labels.team", "payments", "annotations.example.com/owner", "shop-team")},
+			"mydb":    {Params: drivertest.Params("annotations.example.com/owner", "db-team", "numInstances", "3")},
+		},
+		Environment: "prodEnv",
+	}
+	result, err := Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	paths := make([]string, 0, len(result.Artifacts))
+	for _, curArtifact := range result.Artifacts {
+		paths = append(paths, curArtifact.Path)
+	}
+	assert.Equal(t, []string{"base/dbEnvProps.yaml", "base/dbclaim.yaml", "base/mydb.yaml", "base/kustomization.yaml",
+		"overlays/prodEnv/namespace.yaml", "overlays/prodEnv/patches/configmap-dbenvprops.yaml",
+		"overlays/prodEnv/patches/deployment-mydb.yaml", "overlays/prodEnv/kustomization.yaml"}, paths)
+	assert.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- dbEnvProps.yaml
+- dbclaim.yaml
+- mydb.yaml
+`, string(result.Artifacts[3].Content))
+	assert.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+namespace: shop
+resources:
+- ../../base
+- namespace.yaml
+patches:
+- path: patches/configmap-dbenvprops.yaml
+- path: patches/deployment-mydb.yaml
+labels:
+- pairs:
+    team: payments
+  includeTemplates: true
+commonAnnotations:
+  example.com/owner: shop-team
+`, string(result.Artifacts[7].Content))
+
+	// The overlay's namespace and labels are left out of the objects, unlike the settings specific to a resource
+	deployment := string(result.Artifacts[2].Content)
+	assert.NotContains(t, deployment, "namespace:")
+	assert.NotContains(t, deployment, "team: payments")
+	assert.Contains(t, deployment, "example.com/owner: db-team")
+	assert.NotContains(t, string(result.Artifacts[1].Content), "shop-team")
+
+	// The values of the environment are patched by the overlay, so the base is the same for every environment
+	assert.NotContains(t, deployment, "replicas:")
+	assert.NotContains(t, string(result.Artifacts[0].Content), "catalog")
+	assert.Equal(t, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: dbenvprops
+data:
+  db_name: catalog
+`, string(result.Artifacts[5].Content))
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: mydb
+spec:
+  replicas: 3
+`, string(result.Artifacts[6].Content))
+	request.Environment = "stagingEnv"
+	request.PlatformSettings["mydb"].Params["numInstances"].Value = "1"
+	staging, err := Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, result.Artifacts[i], staging.Artifacts[i])
+	}
+	assert.Equal(t, "overlays/stagingEnv/patches/deployment-mydb.yaml", staging.Artifacts[6].Path)
+	assert.Contains(t, string(staging.Artifacts[6].Content), "replicas: 1")
+
+	// The overlay's namespace would replace the namespace of a resource, so they can't both be set
+	request.PlatformSettings["mydb"].Params["namespace"] = &model.Param{Name: "namespace", Value: "db"}
+	_, err = Driver{}.Transform(context.Background(), request)
+	assert.EqualError(t, err, "service.db.postgres:mydb: the namespace 'db' would be replaced by the namespace of the kustomize overlay, 'shop'")
+	delete(request.PlatformSettings["default"].Params, "namespace")
+	result, err = Driver{}.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, "base/00-namespace-db.yaml", result.Artifacts[0].Path)
+	assert.Equal(t, "base/mydb.yaml", result.Artifacts[3].Path)
+	assert.Contains(t, string(result.Artifacts[3].Content), "namespace: db")
+	assert.Equal(t, "overlays/stagingEnv/kustomization.yaml", result.Artifacts[len(result.Artifacts)-1].Path)
+	assert.NotContains(t, string(result.Artifacts[len(result.Artifacts)-1].Content), "namespace:")
+
+	request.PlatformSettings["default"].Params["layout"].Value = "helm"
+	_, err = Driver{}.Transform(context.Background(), request)
+	assert.EqualError(t, err, "'helm' is not a valid value for 'layout' (use flat or kustomize)")
+}
@@ -0,0 +1,228 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"fmt"
+	"path"
+	"rezolvr/drivers"
+	"rezolvr/model"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SettingLayout - the platform setting which selects how the files are laid out: 'flat' (the default) or 'kustomize'.
+// Only the default platform settings are used.
+const SettingLayout = "layout"
+
+// The values of the 'layout' platform setting
+const (
+	LayoutFlat      = "flat"
+	LayoutKustomize = "kustomize"
+)
+
+// FormatKustomization - the output format of kustomization.yaml files
+const FormatKustomization = "kustomization"
+
+// The directories and files of the kustomize layout
+const (
+	KustomizeBaseDir     = "base"
+	KustomizeOverlaysDir = "overlays"
+	KustomizationFile    = "kustomization.yaml"
+	// KustomizePatchesDir - the overlay's directory of patches, which hold the values of the environment
+	KustomizePatchesDir = "patches"
+	// DefaultOverlay - the overlay's directory, when the environment isn't named
+	DefaultOverlay = "default"
+)
+
+// Kustomization - a kustomization.yaml file
+type Kustomization struct {
+	APIVersion        string            `yaml:"apiVersion"`
+	Kind              string            `yaml:"kind"`
+	Namespace         string            `yaml:"namespace,omitempty"`
+	Resources         []string          `yaml:"resources"`
+	Patches           []KustomizePatch  `yaml:"patches,omitempty"`
+	Labels            []KustomizeLabels `yaml:"labels,omitempty"`
+	CommonAnnotations map[string]string `yaml:"commonAnnotations,omitempty"`
+}
+
+// KustomizeLabels - labels added to every object by kustomize. Unlike commonLabels, selectors are left alone, as the
+// selectors of existing Deployments can't be changed.
+type KustomizeLabels struct {
+	Pairs            map[string]string `yaml:"pairs"`
+	IncludeTemplates bool              `yaml:"includeTemplates,omitempty"`
+}
+
+// KustomizePatch - a patch applied by kustomize. The objects it applies to are named by the patch itself.
+type KustomizePatch struct {
+	Path string `yaml:"path"`
+}
+
+// environmentFields - the fields of each kind of object which hold the values of an environment. They're moved out of
+// the base, into a patch within the environment's overlay.
+var environmentFields = map[string][][]string{
+	"Deployment": {{"spec", "replicas"}},
+	"ConfigMap":  {{"data"}, {"binaryData"}},
+	"Secret":     {{"data"}, {"stringData"}},
+}
+
+// overlayMetadata - the namespace, labels and annotations of the default platform settings, which are applied by the
+// overlay when the kustomize layout is selected. Nil for the flat layout.
+func overlayMetadata(platformSettings map[string]*model.Platform) (*objectMetadata, error) {
+	var defaultSettings map[string]*model.Param
+	if platform := platformSettings["default"]; platform != nil {
+		defaultSettings = platform.Params
+	}
+	switch layout := drivers.ParamValue(defaultSettings, SettingLayout); layout {
+	case "", LayoutFlat:
+		return nil, nil
+	case LayoutKustomize:
+		return parseMetadata("the default platform settings", defaultSettings)
+	default:
+		return nil, fmt.Errorf("'%s' is not a valid value for '%s' (use %s or %s)", layout, SettingLayout, LayoutFlat, LayoutKustomize)
+	}
+}
+
+// kustomizeLayout moves the generated files into a base, which is listed by its kustomization.yaml, and adds an overlay
+// for the environment. The base is the same for every environment: the values of the environment (the replicas of
+// Deployments, and the data of ConfigMaps and Secrets) are moved into patches within the overlay, which also sets the
+// namespace, labels and annotations of every object. Files which aren't valid YAML (e.g. from a custom template) are
+// moved into the base as-is.
+func kustomizeLayout(environment string, overlay *objectMetadata, artifacts []model.Artifact) ([]model.Artifact, error) {
+	overlayDir := labelValue(environment)
+	if len(overlayDir) == 0 {
+		overlayDir = DefaultOverlay
+	}
+	overlayDir = path.Join(KustomizeOverlaysDir, overlayDir)
+
+	results := make([]model.Artifact, 0, len(artifacts)+3)
+	patches := make([]model.Artifact, 0)
+	base := Kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization", Resources: []string{}}
+	for _, curArtifact := range artifacts {
+		base.Resources = append(base.Resources, curArtifact.Path)
+		docs, err := DecodeDocuments(curArtifact.Content)
+		if err != nil {
+			logger.Debugf("%s isn't valid YAML, so it's moved into the base as-is: %v", curArtifact.Path, err)
+			docs = nil
+		}
+		objects := make([]interface{}, 0, len(docs))
+		for _, curDoc := range docs {
+			baseDoc, patch := splitEnvironment(curDoc)
+			objects = append(objects, baseDoc)
+			if patch == nil {
+				continue
+			}
+			kind, name := objectKey(patch)
+			content, err := marshalObjects([]interface{}{patch})
+			if err != nil {
+				return nil, err
+			}
+			patchPath := path.Join(KustomizePatchesDir, strings.ToLower(kind)+"-"+name+".yaml")
+			patches = append(patches, model.Artifact{Path: path.Join(overlayDir, patchPath), Format: FormatManifest, Content: content})
+		}
+		if len(objects) > 0 {
+			content, err := marshalObjects(objects)
+			if err != nil {
+				return nil, err
+			}
+			curArtifact.Content = content
+		}
+		curArtifact.Path = path.Join(KustomizeBaseDir, curArtifact.Path)
+		results = append(results, curArtifact)
+	}
+	sort.Strings(base.Resources)
+	content, err := marshalObjects([]interface{}{base})
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, model.Artifact{Path: path.Join(KustomizeBaseDir, KustomizationFile), Format: FormatKustomization, Content: content})
+
+	kustomization := Kustomization{APIVersion: base.APIVersion, Kind: base.Kind, Namespace: overlay.namespace,
+		Resources: []string{path.Join("..", "..", KustomizeBaseDir)}}
+	if namespace := newNamespace(overlay.namespace, overlay.createNamespace); namespace != nil {
+		content, err := marshalObjects([]interface{}{namespace})
+		if err != nil {
+			return nil, err
+		}
+		kustomization.Resources = append(kustomization.Resources, "namespace.yaml")
+		results = append(results, model.Artifact{Path: path.Join(overlayDir, "namespace.yaml"), Format: FormatManifest, Content: content})
+	}
+	sort.Slice(patches, func(i, j int) bool { return patches[i].Path < patches[j].Path })
+	for _, curPatch := range patches {
+		kustomization.Patches = append(kustomization.Patches, KustomizePatch{Path: strings.TrimPrefix(curPatch.Path, overlayDir+"/")})
+	}
+	results = append(results, patches...)
+	if len(overlay.labels) > 0 {
+		kustomization.Labels = []KustomizeLabels{{Pairs: overlay.labels, IncludeTemplates: true}}
+	}
+	if len(overlay.annotations) > 0 {
+		kustomization.CommonAnnotations = overlay.annotations
+	}
+	if content, err = marshalObjects([]interface{}{kustomization}); err != nil {
+		return nil, err
+	}
+	results = append(results, model.Artifact{Path: path.Join(overlayDir, KustomizationFile), Format: FormatKustomization, Content: content})
+	return results, nil
+}
+
+// splitEnvironment splits an object into the part which is the same for every environment, and a patch which holds
+// the values of the environment (nil when it has none). The patch names the object by its kind, name and namespace.
+func splitEnvironment(doc yaml.MapSlice) (yaml.MapSlice, yaml.MapSlice) {
+	kind, name := objectKey(doc)
+	var values yaml.MapSlice
+	for _, curPath := range environmentFields[kind] {
+		value := lookupPath(doc, curPath)
+		if value == nil {
+			continue
+		}
+		values = mergePatch(values, nestedValue(curPath, value))
+		doc = mergePatch(doc, nestedValue(curPath, nil))
+	}
+	if len(values) == 0 {
+		return doc, nil
+	}
+	metadata := yaml.MapSlice{{Key: "name", Value: name}}
+	if docMetadata, ok := Lookup(doc, "metadata").(yaml.MapSlice); ok {
+		if namespace := Lookup(docMetadata, "namespace"); namespace != nil {
+			metadata = append(metadata, yaml.MapItem{Key: "namespace", Value: namespace})
+		}
+	}
+	patch := yaml.MapSlice{{Key: "apiVersion", Value: Lookup(doc, "apiVersion")}, {Key: "kind", Value: kind},
+		{Key: "metadata", Value: metadata}}
+	return doc, append(patch, values...)
+}
+
+// lookupPath - the value at a path of keys within a document, or nil when it isn't set
+func lookupPath(doc yaml.MapSlice, keys []string) interface{} {
+	var value interface{} = doc
+	for _, curKey := range keys {
+		curMap, ok := value.(yaml.MapSlice)
+		if !ok {
+			return nil
+		}
+		value = Lookup(curMap, curKey)
+	}
+	return value
+}
+
+// nestedValue - a document with a value at a path of keys
+func nestedValue(keys []string, value interface{}) yaml.MapSlice {
+	for i := len(keys) - 1; i > 0; i-- {
+		value = yaml.MapSlice{{Key: keys[i], Value: value}}
+	}
+	return yaml.MapSlice{{Key: keys[0], Value: value}}
+}
@@ -0,0 +1,205 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"fmt"
+	"regexp"
+	"rezolvr/drivers"
+	"rezolvr/model"
+	"strings"
+)
+
+// Labels added to every object, so that it can be traced back to the component in the state
+const (
+	LabelName      = "app.kubernetes.io/name"
+	LabelInstance  = "app.kubernetes.io/instance"
+	LabelComponent = "app.kubernetes.io/component"
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	// LabelRezolvrComponent - the ID of the component within the state. IDs can't be used as label values as-is, so
+	// the exact ID is kept in the annotation of the same name.
+	LabelRezolvrComponent = "rezolvr.io/component"
+)
+
+// Platform settings which control the metadata of the generated objects. Each label and annotation is a separate
+// setting, e.g. 'labels.team'.
+const (
+	SettingNamespace        = "namespace"
+	SettingCreateNamespace  = "createNamespace"
+	SettingLabelPrefix      = "labels."
+	SettingAnnotationPrefix = "annotations."
+)
+
+// ManagedBy - the value of the app.kubernetes.io/managed-by label
+const ManagedBy = "rezolvr"
+
+var (
+	// dnsLabel - the names of namespaces (RFC 1123)
+	dnsLabel = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	// qualifiedName - the name part of a label or annotation key, and the value of a label
+	qualifiedName = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$`)
+	// dnsSubdomain - the prefix of a label or annotation key
+	dnsSubdomain = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	// invalidLabelChars - characters which aren't allowed in a label value
+	invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// objectMetadata - the namespace, labels and annotations shared by the objects generated for a provided resource
+type objectMetadata struct {
+	namespace       string
+	createNamespace bool
+	labels          map[string]string
+	annotations     map[string]string
+}
+
+// labelValue converts a value (e.g. a component ID) into a valid label value
+func labelValue(value string) string {
+	value = invalidLabelChars.ReplaceAllString(value, "-")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return strings.Trim(value, "-_.")
+}
+
+func validKeyName(key string) bool {
+	name := key
+	if slash := strings.Index(key, "/"); slash >= 0 {
+		prefix := key[:slash]
+		name = key[slash+1:]
+		if len(prefix) == 0 || len(prefix) > 253 || !dnsSubdomain.MatchString(prefix) {
+			return false
+		}
+	}
+	return len(name) <= 63 && qualifiedName.MatchString(name)
+}
+
+// parseMetadata reads the namespace, labels and annotations from platform settings. Errors are prefixed by the source
+// of the settings (e.g. the resource's ID).
+func parseMetadata(source string, platform map[string]*model.Param) (*objectMetadata, error) {
+	metadata := &objectMetadata{labels: make(map[string]string), annotations: make(map[string]string), createNamespace: true}
+	metadata.namespace = drivers.ParamValue(platform, SettingNamespace)
+	if len(metadata.namespace) > 0 && (len(metadata.namespace) > 63 || !dnsLabel.MatchString(metadata.namespace)) {
+		return nil, fmt.Errorf("%s: '%s' is not a valid namespace (use lower case letters, digits and '-')", source, metadata.namespace)
+	}
+	if createNamespace := drivers.ParamValue(platform, SettingCreateNamespace); len(createNamespace) > 0 {
+		metadata.createNamespace = createNamespace == "true"
+	}
+	for _, curParam := range drivers.SortedParams(platform) {
+		if key := strings.TrimPrefix(curParam.Name, SettingLabelPrefix); key != curParam.Name {
+			if !validKeyName(key) {
+				return nil, fmt.Errorf("%s: '%s' is not a valid label", source, key)
+			}
+			if len(curParam.Value) > 0 && (len(curParam.Value) > 63 || !qualifiedName.MatchString(curParam.Value)) {
+				return nil, fmt.Errorf("%s: '%s' is not a valid value for the '%s' label", source, curParam.Value, key)
+			}
+			metadata.labels[key] = curParam.Value
+		} else if key := strings.TrimPrefix(curParam.Name, SettingAnnotationPrefix); key != curParam.Name {
+			if !validKeyName(key) {
+				return nil, fmt.Errorf("%s: '%s' is not a valid annotation", source, key)
+			}
+			metadata.annotations[key] = curParam.Value
+		}
+	}
+	return metadata, nil
+}
+
+// resolveMetadata reads the namespace, labels and annotations from the platform settings, and adds the standard labels.
+// Those which are applied by a kustomize overlay instead are left out. As the overlay's namespace replaces the namespace
+// of every object, a resource can't have a namespace of its own when the overlay sets one.
+func (bc *buildContext) resolveMetadata() error {
+	metadata, err := parseMetadata(bc.resourceID(), bc.platform)
+	if err != nil {
+		return err
+	}
+	if overlay := bc.overlay; overlay != nil {
+		if len(overlay.namespace) > 0 {
+			if metadata.namespace != overlay.namespace {
+				return fmt.Errorf("%s: the namespace '%s' would be replaced by the namespace of the kustomize overlay, '%s'",
+					bc.resourceID(), metadata.namespace, overlay.namespace)
+			}
+			metadata.namespace = ""
+		}
+		for k, v := range overlay.labels {
+			if metadata.labels[k] == v {
+				delete(metadata.labels, k)
+			}
+		}
+		for k, v := range overlay.annotations {
+			if metadata.annotations[k] == v {
+				delete(metadata.annotations, k)
+			}
+		}
+	}
+	// The standard labels take precedence over those from the platform settings
+	metadata.labels[LabelName] = labelValue(bc.component.Name)
+	metadata.labels[LabelInstance] = labelValue(bc.provides.Name)
+	if componentType := labelValue(strings.TrimPrefix(bc.component.Type, "resource.")); len(componentType) > 0 {
+		metadata.labels[LabelComponent] = componentType
+	}
+	metadata.labels[LabelManagedBy] = ManagedBy
+	metadata.labels[LabelRezolvrComponent] = labelValue(bc.component.ID())
+	metadata.annotations[LabelRezolvrComponent] = bc.component.ID()
+	bc.metadata = metadata
+	return nil
+}
+
+// objectMeta - the metadata of an object, with the common labels and annotations. Cluster-scoped objects (e.g.
+// PersistentVolumes) don't have a namespace.
+func (bc *buildContext) objectMeta(name string, namespaced bool) ObjectMeta {
+	meta := ObjectMeta{Name: name, Labels: bc.podLabels(nil), Annotations: make(map[string]string)}
+	if bc.metadata == nil {
+		return meta
+	}
+	if namespaced {
+		meta.Namespace = bc.metadata.namespace
+	}
+	for k, v := range bc.metadata.annotations {
+		meta.Annotations[k] = v
+	}
+	return meta
+}
+
+// podLabels - the common labels, along with the given ones (e.g. those used by a selector)
+func (bc *buildContext) podLabels(extra map[string]string) map[string]string {
+	labels := make(map[string]string)
+	if bc.metadata != nil {
+		for k, v := range bc.metadata.labels {
+			labels[k] = v
+		}
+	}
+	for k, v := range extra {
+		labels[k] = v
+	}
+	return labels
+}
+
+// namespaceObject - the Namespace which holds the objects of a provided resource, when it should be generated
+func (bc *buildContext) namespaceObject() *Namespace {
+	if bc.metadata == nil {
+		return nil
+	}
+	return newNamespace(bc.metadata.namespace, bc.metadata.createNamespace)
+}
+
+// newNamespace - a Namespace, unless it's the default namespace, or it shouldn't be created
+func newNamespace(name string, create bool) *Namespace {
+	if len(name) == 0 || name == "default" || !create {
+		return nil
+	}
+	return &Namespace{
+		TypeMeta: TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		Metadata: ObjectMeta{Name: name, Labels: map[string]string{LabelManagedBy: ManagedBy}},
+	}
+}
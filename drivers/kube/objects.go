@@ -0,0 +1,387 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+// The types below mirror the subset of the Kubernetes API (k8s.io/api) which the driver generates. Fields are declared
+// in the order kubectl prints them, so that the generated YAML reads naturally.
+
+// TypeMeta identifies the kind of an object
+type TypeMeta struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+}
+
+// ObjectMeta is the metadata shared by every object
+type ObjectMeta struct {
+	Name        string            `yaml:"name,omitempty"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// Namespace - v1 Namespace
+type Namespace struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta `yaml:"metadata"`
+}
+
+// Deployment - apps/v1 Deployment
+type Deployment struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta     `yaml:"metadata"`
+	Spec     DeploymentSpec `yaml:"spec"`
+}
+
+// DeploymentSpec - the desired state of a Deployment
+type DeploymentSpec struct {
+	Selector LabelSelector   `yaml:"selector"`
+	Replicas *int32          `yaml:"replicas,omitempty"`
+	Template PodTemplateSpec `yaml:"template"`
+}
+
+// LabelSelector selects objects by their labels
+type LabelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+// PodTemplateSpec describes the pods created by a Deployment
+type PodTemplateSpec struct {
+	Metadata ObjectMeta `yaml:"metadata"`
+	Spec     PodSpec    `yaml:"spec"`
+}
+
+// PodSpec - the containers and volumes of a pod
+type PodSpec struct {
+	Containers                []Container                `yaml:"containers"`
+	Volumes                   []Volume                   `yaml:"volumes,omitempty"`
+	TopologySpreadConstraints []TopologySpreadConstraint `yaml:"topologySpreadConstraints,omitempty"`
+}
+
+// TopologySpreadConstraint - how pods are spread across a topology (e.g. zones)
+type TopologySpreadConstraint struct {
+	MaxSkew           int32         `yaml:"maxSkew"`
+	TopologyKey       string        `yaml:"topologyKey"`
+	WhenUnsatisfiable string        `yaml:"whenUnsatisfiable"`
+	LabelSelector     LabelSelector `yaml:"labelSelector"`
+}
+
+// Container - a single container within a pod
+type Container struct {
+	Name            string               `yaml:"name"`
+	Image           string               `yaml:"image"`
+	ImagePullPolicy string               `yaml:"imagePullPolicy,omitempty"`
+	VolumeMounts    []VolumeMount        `yaml:"volumeMounts,omitempty"`
+	Ports           []ContainerPort      `yaml:"ports,omitempty"`
+	EnvFrom         []EnvFromSource      `yaml:"envFrom,omitempty"`
+	Env             []EnvVar             `yaml:"env,omitempty"`
+	Resources       ResourceRequirements `yaml:"resources,omitempty"`
+	ReadinessProbe  *Probe               `yaml:"readinessProbe,omitempty"`
+	LivenessProbe   *Probe               `yaml:"livenessProbe,omitempty"`
+}
+
+// Probe - a periodic check of a container's health
+type Probe struct {
+	HTTPGet             *HTTPGetAction   `yaml:"httpGet,omitempty"`
+	TCPSocket           *TCPSocketAction `yaml:"tcpSocket,omitempty"`
+	InitialDelaySeconds int32            `yaml:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int32            `yaml:"periodSeconds,omitempty"`
+	FailureThreshold    int32            `yaml:"failureThreshold,omitempty"`
+}
+
+// HTTPGetAction - an HTTP GET request, which succeeds with a 2xx or 3xx status
+type HTTPGetAction struct {
+	Path string `yaml:"path"`
+	Port int32  `yaml:"port"`
+}
+
+// TCPSocketAction - a TCP connection, which succeeds when the port is open
+type TCPSocketAction struct {
+	Port int32 `yaml:"port"`
+}
+
+// ContainerPort - a port exposed by a container
+type ContainerPort struct {
+	ContainerPort int32 `yaml:"containerPort"`
+}
+
+// EnvVar - an environment variable, with either a value or a reference to one
+type EnvVar struct {
+	Name      string        `yaml:"name"`
+	Value     string        `yaml:"value,omitempty"`
+	ValueFrom *EnvVarSource `yaml:"valueFrom,omitempty"`
+}
+
+// EnvVarSource - where an environment variable's value comes from
+type EnvVarSource struct {
+	ConfigMapKeyRef *KeySelector `yaml:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *KeySelector `yaml:"secretKeyRef,omitempty"`
+}
+
+// EnvFromSource - every key of a ConfigMap or Secret, as environment variables
+type EnvFromSource struct {
+	ConfigMapRef *LocalObjectReference `yaml:"configMapRef,omitempty"`
+	SecretRef    *LocalObjectReference `yaml:"secretRef,omitempty"`
+}
+
+// LocalObjectReference refers to an object in the same namespace
+type LocalObjectReference struct {
+	Name string `yaml:"name"`
+}
+
+// KeySelector selects a key within a Secret (or ConfigMap)
+type KeySelector struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+// VolumeMount - where a volume is mounted within a container
+type VolumeMount struct {
+	MountPath string `yaml:"mountPath"`
+	Name      string `yaml:"name"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+	SubPath   string `yaml:"subPath,omitempty"`
+}
+
+// Volume - a volume available to the containers of a pod
+type Volume struct {
+	Name                  string             `yaml:"name"`
+	PersistentVolumeClaim *ClaimVolumeSource `yaml:"persistentVolumeClaim,omitempty"`
+}
+
+// ClaimVolumeSource - a volume backed by a PersistentVolumeClaim
+type ClaimVolumeSource struct {
+	ClaimName string `yaml:"claimName"`
+}
+
+// Service - v1 Service
+type Service struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta  `yaml:"metadata"`
+	Spec     ServiceSpec `yaml:"spec"`
+}
+
+// ServiceSpec - how a Service is exposed, and which pods it selects
+type ServiceSpec struct {
+	Type     string            `yaml:"type,omitempty"`
+	Selector map[string]string `yaml:"selector"`
+	Ports    []ServicePort     `yaml:"ports"`
+}
+
+// ServicePort - a port exposed by a Service
+type ServicePort struct {
+	Protocol   string `yaml:"protocol,omitempty"`
+	Port       int32  `yaml:"port"`
+	TargetPort int32  `yaml:"targetPort,omitempty"`
+	NodePort   int32  `yaml:"nodePort,omitempty"`
+}
+
+// PersistentVolume - v1 PersistentVolume
+type PersistentVolume struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta           `yaml:"metadata"`
+	Spec     PersistentVolumeSpec `yaml:"spec"`
+}
+
+// PersistentVolumeSpec - the storage provided by a PersistentVolume
+type PersistentVolumeSpec struct {
+	Capacity                      map[string]string     `yaml:"capacity,omitempty"`
+	VolumeMode                    string                `yaml:"volumeMode,omitempty"`
+	AccessModes                   []string              `yaml:"accessModes,omitempty"`
+	HostPath                      *HostPathVolumeSource `yaml:"hostPath,omitempty"`
+	PersistentVolumeReclaimPolicy string                `yaml:"persistentVolumeReclaimPolicy,omitempty"`
+	StorageClassName              string                `yaml:"storageClassName,omitempty"`
+}
+
+// HostPathVolumeSource - a volume backed by a directory on the node
+type HostPathVolumeSource struct {
+	Path string `yaml:"path"`
+}
+
+// PersistentVolumeClaim - v1 PersistentVolumeClaim
+type PersistentVolumeClaim struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta                `yaml:"metadata"`
+	Spec     PersistentVolumeClaimSpec `yaml:"spec"`
+}
+
+// PersistentVolumeClaimSpec - the storage requested by a PersistentVolumeClaim
+type PersistentVolumeClaimSpec struct {
+	StorageClassName string               `yaml:"storageClassName,omitempty"`
+	VolumeMode       string               `yaml:"volumeMode,omitempty"`
+	AccessModes      []string             `yaml:"accessModes,omitempty"`
+	Resources        ResourceRequirements `yaml:"resources,omitempty"`
+}
+
+// ResourceRequirements - the compute or storage resources requested
+type ResourceRequirements struct {
+	Requests map[string]string `yaml:"requests,omitempty"`
+	Limits   map[string]string `yaml:"limits,omitempty"`
+}
+
+// Secret - v1 Secret. The values within data are base64-encoded.
+type Secret struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta        `yaml:"metadata"`
+	Type     string            `yaml:"type,omitempty"`
+	Data     map[string]string `yaml:"data,omitempty"`
+}
+
+// ConfigMap - v1 ConfigMap
+type ConfigMap struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta        `yaml:"metadata"`
+	Data     map[string]string `yaml:"data,omitempty"`
+}
+
+// PodDisruptionBudget - policy/v1 PodDisruptionBudget
+type PodDisruptionBudget struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta              `yaml:"metadata"`
+	Spec     PodDisruptionBudgetSpec `yaml:"spec"`
+}
+
+// PodDisruptionBudgetSpec - the pods which must remain available during voluntary disruptions
+type PodDisruptionBudgetSpec struct {
+	MinAvailable   *int32        `yaml:"minAvailable,omitempty"`
+	MaxUnavailable *int32        `yaml:"maxUnavailable,omitempty"`
+	Selector       LabelSelector `yaml:"selector"`
+}
+
+// HorizontalPodAutoscaler - autoscaling/v2 HorizontalPodAutoscaler
+type HorizontalPodAutoscaler struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta                  `yaml:"metadata"`
+	Spec     HorizontalPodAutoscalerSpec `yaml:"spec"`
+}
+
+// HorizontalPodAutoscalerSpec - the workload which is scaled, and how
+type HorizontalPodAutoscalerSpec struct {
+	ScaleTargetRef CrossVersionObjectReference `yaml:"scaleTargetRef"`
+	MinReplicas    *int32                      `yaml:"minReplicas,omitempty"`
+	MaxReplicas    int32                       `yaml:"maxReplicas"`
+	Metrics        []MetricSpec                `yaml:"metrics,omitempty"`
+}
+
+// CrossVersionObjectReference refers to the object which is scaled
+type CrossVersionObjectReference struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+}
+
+// MetricSpec - a metric which scaling is based on. Only resource metrics are generated.
+type MetricSpec struct {
+	Type     string                `yaml:"type"`
+	Resource *ResourceMetricSource `yaml:"resource,omitempty"`
+}
+
+// ResourceMetricSource - the utilization of a resource (e.g. cpu) by the pods
+type ResourceMetricSource struct {
+	Name   string       `yaml:"name"`
+	Target MetricTarget `yaml:"target"`
+}
+
+// MetricTarget - the value a metric aims for
+type MetricTarget struct {
+	Type               string `yaml:"type"`
+	AverageUtilization *int32 `yaml:"averageUtilization,omitempty"`
+}
+
+// Ingress routes external HTTP(S) traffic to the Services of one or more workloads
+type Ingress struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta  `yaml:"metadata"`
+	Spec     IngressSpec `yaml:"spec"`
+}
+
+// IngressSpec - the ingress class, and the rules which map each host and path to a Service
+type IngressSpec struct {
+	IngressClassName string        `yaml:"ingressClassName,omitempty"`
+	TLS              []IngressTLS  `yaml:"tls,omitempty"`
+	Rules            []IngressRule `yaml:"rules"`
+}
+
+// IngressTLS - the Secret holding the certificate of the hosts
+type IngressTLS struct {
+	Hosts      []string `yaml:"hosts,omitempty"`
+	SecretName string   `yaml:"secretName,omitempty"`
+}
+
+// IngressRule - the paths of a host (or of every host, when it isn't set)
+type IngressRule struct {
+	Host string               `yaml:"host,omitempty"`
+	HTTP HTTPIngressRuleValue `yaml:"http"`
+}
+
+// HTTPIngressRuleValue - the paths of an ingress rule
+type HTTPIngressRuleValue struct {
+	Paths []HTTPIngressPath `yaml:"paths"`
+}
+
+// HTTPIngressPath maps a path to a Service
+type HTTPIngressPath struct {
+	Path     string         `yaml:"path"`
+	PathType string         `yaml:"pathType"`
+	Backend  IngressBackend `yaml:"backend"`
+}
+
+// IngressBackend - the Service which handles the requests of a path
+type IngressBackend struct {
+	Service IngressServiceBackend `yaml:"service"`
+}
+
+// IngressServiceBackend - the name and port of a Service
+type IngressServiceBackend struct {
+	Name string             `yaml:"name"`
+	Port ServiceBackendPort `yaml:"port"`
+}
+
+// ServiceBackendPort - the port of a Service, by number
+type ServiceBackendPort struct {
+	Number int32 `yaml:"number"`
+}
+
+// Route exposes a Service outside of an OpenShift cluster
+type Route struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta `yaml:"metadata"`
+	Spec     RouteSpec  `yaml:"spec"`
+}
+
+// RouteSpec - the host and path of a route, and the Service it leads to
+type RouteSpec struct {
+	Host string               `yaml:"host,omitempty"`
+	Path string               `yaml:"path,omitempty"`
+	To   RouteTargetReference `yaml:"to"`
+	Port *RoutePort           `yaml:"port,omitempty"`
+	TLS  *TLSConfig           `yaml:"tls,omitempty"`
+}
+
+// RouteTargetReference - the Service a route leads to
+type RouteTargetReference struct {
+	Kind string `yaml:"kind"`
+	Name string `yaml:"name"`
+}
+
+// RoutePort - the port of the Service a route leads to
+type RoutePort struct {
+	TargetPort int32 `yaml:"targetPort"`
+}
+
+// TLSConfig - how TLS is terminated for a route
+type TLSConfig struct {
+	Termination                   string `yaml:"termination"`
+	InsecureEdgeTerminationPolicy string `yaml:"insecureEdgeTerminationPolicy,omitempty"`
+}
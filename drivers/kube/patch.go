@@ -0,0 +1,161 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// PatchSuffix - appended to a resource type, to name the template which patches the objects generated for it.
+// e.g. service.web.app.patch.template
+const PatchSuffix = ".patch"
+
+// marshalObjects writes objects as a series of YAML documents
+func marshalObjects(objects []interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	for i, curObject := range objects {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		out, err := yaml.Marshal(curObject)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(out)
+	}
+	return buf.Bytes(), nil
+}
+
+// applyPatches merges each document of a (rendered) patch template into the generated object with the same kind, and
+// name when the patch has one. A patch without a name applies to every object of its kind. As with a JSON merge patch
+// (RFC 7386), maps are merged, other values replace the generated ones, and null removes a field.
+func applyPatches(objects []interface{}, patchSource []byte) ([]interface{}, error) {
+	patches, err := DecodeDocuments(patchSource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %v", err)
+	}
+	if len(patches) == 0 {
+		return objects, nil
+	}
+	// Patches are applied to the generic form of each object, which keeps the field order of the typed object
+	docs := make([]yaml.MapSlice, 0, len(objects))
+	for _, curObject := range objects {
+		out, err := yaml.Marshal(curObject)
+		if err != nil {
+			return nil, err
+		}
+		doc := yaml.MapSlice{}
+		if err := yaml.Unmarshal(out, &doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	for _, curPatch := range patches {
+		kind, name := objectKey(curPatch)
+		if len(kind) == 0 {
+			return nil, fmt.Errorf("invalid patch: each document must have a 'kind'")
+		}
+		matched := false
+		for i, curDoc := range docs {
+			docKind, docName := objectKey(curDoc)
+			if docKind == kind && (len(name) == 0 || docName == name) {
+				docs[i] = mergePatch(curDoc, curPatch)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("invalid patch: no %s named '%s' was generated", kind, name)
+		}
+	}
+	results := make([]interface{}, 0, len(docs))
+	for _, curDoc := range docs {
+		results = append(results, curDoc)
+	}
+	return results, nil
+}
+
+// DecodeDocuments - the documents of a YAML file (separated by '---'), keeping the order of their fields. Empty
+// documents are skipped.
+func DecodeDocuments(source []byte) ([]yaml.MapSlice, error) {
+	var results []yaml.MapSlice
+	decoder := yaml.NewDecoder(bytes.NewReader(source))
+	for {
+		doc := yaml.MapSlice{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc) > 0 {
+			results = append(results, doc)
+		}
+	}
+}
+
+// objectKey - the kind and name of an object
+func objectKey(doc yaml.MapSlice) (string, string) {
+	kind, _ := Lookup(doc, "kind").(string)
+	name := ""
+	if metadata, ok := Lookup(doc, "metadata").(yaml.MapSlice); ok {
+		name, _ = Lookup(metadata, "name").(string)
+	}
+	return kind, name
+}
+
+// Lookup - the value of a key within a document, or nil when it isn't set
+func Lookup(doc yaml.MapSlice, key string) interface{} {
+	for _, item := range doc {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// mergePatch merges patch into target, and returns the result
+func mergePatch(target yaml.MapSlice, patch yaml.MapSlice) yaml.MapSlice {
+	for _, patchItem := range patch {
+		index := -1
+		for i, targetItem := range target {
+			if targetItem.Key == patchItem.Key {
+				index = i
+				break
+			}
+		}
+		switch {
+		case patchItem.Value == nil:
+			if index >= 0 {
+				target = append(target[:index], target[index+1:]...)
+			}
+		case index < 0:
+			target = append(target, patchItem)
+		default:
+			patchMap, patchIsMap := patchItem.Value.(yaml.MapSlice)
+			targetMap, targetIsMap := target[index].Value.(yaml.MapSlice)
+			if patchIsMap && targetIsMap {
+				target[index].Value = mergePatch(targetMap, patchMap)
+			} else {
+				target[index].Value = patchItem.Value
+			}
+		}
+	}
+	return target
+}
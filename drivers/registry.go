@@ -0,0 +1,84 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drivers holds the registry of drivers which are compiled into rezolvr. Each built-in driver registers itself
+// from an init function, so importing the driver's package is enough to make it available.
+package drivers
+
+import (
+	"fmt"
+	"io/fs"
+	"rezolvr/model"
+	"sort"
+	"sync"
+)
+
+// Factory creates a new instance of a driver
+type Factory func() model.RezolvrDriver
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Factory)
+	templateFiles = make(map[string]fs.FS)
+)
+
+// Register makes a driver available by name. Registering the same name twice is a programming error, and panics.
+func Register(name string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if factory == nil {
+		panic("drivers: Register factory is nil for driver " + name)
+	}
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("drivers: Register called twice for driver %s", name))
+	}
+	registry[name] = factory
+}
+
+// RegisterTemplates makes a driver's built-in templates available, e.g. to 'rezolvr templates'
+func RegisterTemplates(name string, templates fs.FS) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	templateFiles[name] = templates
+}
+
+// Templates returns a driver's built-in templates, or nil when it has none
+func Templates(name string) fs.FS {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return templateFiles[name]
+}
+
+// Lookup creates an instance of a registered driver
+func Lookup(name string) (model.RezolvrDriver, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	factory, ok := registry[name]
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
+
+// Names lists the registered drivers, in alphabetical order
+func Names() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := make([]string, 0, len(registry))
+	for k := range registry {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,55 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drivers
+
+import (
+	"context"
+	"rezolvr/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDriver struct{}
+
+func (td testDriver) Name() string                     { return "test" }
+func (td testDriver) Version() string                  { return "0.0.1" }
+func (td testDriver) SetLogger(newLogger model.Logger) {}
+func (td testDriver) Capabilities() model.Capabilities {
+	return model.Capabilities{APIVersion: model.DriverAPIVersion}
+}
+func (td testDriver) Validate(ctx context.Context, request *model.TransformRequest) error { return nil }
+func (td testDriver) Transform(ctx context.Context, request *model.TransformRequest) (*model.TransformResult, error) {
+	return &model.TransformResult{}, nil
+}
+
+func Test_Registry(t *testing.T) {
+	Register("test-b", func() model.RezolvrDriver { return testDriver{} })
+	Register("test-a", func() model.RezolvrDriver { return testDriver{} })
+
+	driver, ok := Lookup("test-a")
+	assert.True(t, ok)
+	assert.NotNil(t, driver)
+	_, ok = Lookup("missing")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"test-a", "test-b"}, Names())
+
+	assert.Panics(t, func() {
+		Register("test-a", func() model.RezolvrDriver { return testDriver{} })
+	})
+	assert.Panics(t, func() {
+		Register("test-c", nil)
+	})
+}
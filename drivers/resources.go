@@ -0,0 +1,134 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drivers
+
+import (
+	"fmt"
+	"io/fs"
+	"rezolvr/model"
+	"sort"
+)
+
+// MustSub - the subdirectory of a file system, such as a driver's embedded templates
+func MustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// ResourceID - the ID of a resource, e.g. service.web.app:catalogapp
+func ResourceID(r *model.Resource) string {
+	return r.Type + model.IDSeparator + r.Name
+}
+
+// ParamValue - the value of a param, or an empty string when it isn't set
+func ParamValue(params map[string]*model.Param, name string) string {
+	if param := params[name]; param != nil {
+		return param.Value
+	}
+	return ""
+}
+
+// RequiredParam - the value of a param of a resource, which must be set
+func RequiredParam(r *model.Resource, name string) (string, error) {
+	value := ParamValue(r.Params, name)
+	if len(value) == 0 {
+		return "", fmt.Errorf("%s: missing a value for the '%s' param", ResourceID(r), name)
+	}
+	return value, nil
+}
+
+// ProvidedName - the name of a provided resource within the platform (its 'name' param), or the name of the resource
+func ProvidedName(r *model.Resource) string {
+	if name := ParamValue(r.Params, "name"); len(name) > 0 {
+		return name
+	}
+	return r.Name
+}
+
+// SortedParams - the params of a resource, sorted by name
+func SortedParams(params map[string]*model.Param) []*model.Param {
+	names := make([]string, 0, len(params))
+	for k := range params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	results := make([]*model.Param, 0, len(names))
+	for _, k := range names {
+		results = append(results, params[k])
+	}
+	return results
+}
+
+// SortedUses - the resources used by a component, sorted by their key
+func SortedUses(c *model.Component) []*model.Resource {
+	return sortedResources(c.Uses)
+}
+
+// SortedProvides - the resources provided by a component, sorted by their key
+func SortedProvides(c *model.Component) []*model.Resource {
+	return sortedResources(c.Provides)
+}
+
+func sortedResources(resources map[string]*model.Resource) []*model.Resource {
+	keys := make([]string, 0, len(resources))
+	for k := range resources {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	results := make([]*model.Resource, 0, len(keys))
+	for _, k := range keys {
+		results = append(results, resources[k])
+	}
+	return results
+}
+
+// AllComponents - every component within the state, along with the updated components, which take precedence
+func AllComponents(request *model.TransformRequest) map[string]*model.Component {
+	results := make(map[string]*model.Component)
+	if request.State != nil {
+		for k, v := range request.State.Components {
+			results[k] = v
+		}
+	}
+	for k, v := range request.UpdatedComponents {
+		results[k] = v
+	}
+	return results
+}
+
+// ProvidedResources - every resource provided by the components, by ID
+func ProvidedResources(components map[string]*model.Component) map[string]*model.Resource {
+	results := make(map[string]*model.Resource)
+	for _, curComponent := range components {
+		for _, curProvides := range curComponent.Provides {
+			results[ResourceID(curProvides)] = curProvides
+		}
+	}
+	return results
+}
+
+// SkipOutput - some resources do not generate output, because they're external resources, or have been explicitly
+// opted out. Check the platform settings for this resource
+func SkipOutput(curProvides *model.Resource, platformSettings map[string]*model.Platform, logger model.Logger) bool {
+	curSetting := model.OutputExcusedBy(curProvides.Name, platformSettings)
+	if len(curSetting) == 0 {
+		return false
+	}
+	logger.Debugf("Based on the platform settings, output will not be generated for: %s. (%s=true)", curProvides.Name, curSetting)
+	return true
+}
@@ -0,0 +1,54 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drivers
+
+import (
+	"rezolvr/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Resources(t *testing.T) {
+	volume := &model.Resource{Name: "dbvolume", Type: "storage.volume", Params: map[string]*model.Param{
+		"name": {Name: "name", Value: "pgdata"}, "size": {Name: "size"}}}
+	assert.Equal(t, "storage.volume:dbvolume", ResourceID(volume))
+	assert.Equal(t, "pgdata", ProvidedName(volume))
+	assert.Equal(t, "dbvolume", ProvidedName(&model.Resource{Name: "dbvolume", Type: "storage.volume"}))
+	_, err := RequiredParam(volume, "size")
+	assert.EqualError(t, err, "storage.volume:dbvolume: missing a value for the 'size' param")
+
+	component := &model.Component{Name: "volumes", Provides: map[string]*model.Resource{
+		"storage.volume:dbvolume": volume,
+		"storage.volume:cache":    {Name: "cache", Type: "storage.volume"},
+	}}
+	sorted := SortedProvides(component)
+	assert.Equal(t, []string{"cache", "dbvolume"}, []string{sorted[0].Name, sorted[1].Name})
+	assert.Equal(t, []string{"name", "size"}, []string{SortedParams(volume.Params)[0].Name, SortedParams(volume.Params)[1].Name})
+
+	// Updated components take precedence over those in the state
+	updated := &model.Component{Name: "volumes"}
+	components := AllComponents(&model.TransformRequest{UpdatedComponents: map[string]*model.Component{"volumes": updated},
+		State: &model.State{Components: map[string]*model.Component{"volumes": component}}})
+	assert.Same(t, updated, components["volumes"])
+	assert.Len(t, ProvidedResources(map[string]*model.Component{"volumes": component}), 2)
+
+	platformSettings := map[string]*model.Platform{
+		"dbvolume": {Params: map[string]*model.Param{"isExternal": {Name: "isExternal", Value: "true"}}},
+		"cache":    {Params: map[string]*model.Param{"skipOutput": {Name: "skipOutput", Value: "false"}}},
+	}
+	assert.True(t, SkipOutput(volume, platformSettings, model.GetLogger()))
+	assert.False(t, SkipOutput(sorted[0], platformSettings, model.GetLogger()))
+}
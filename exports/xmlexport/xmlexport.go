@@ -18,7 +18,6 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"rezolvr/model"
 )
@@ -96,7 +95,8 @@ func positionCells(allCells map[string]*mxCell) {
 
 // ExportState exports the contents of the state to a format that can be read by a diagramming tool
 func ExportState(state *model.State, exportFilename string) error {
-	log.Printf("Exporting state to file: %v\n", exportFilename)
+	logger := model.GetLogger()
+	logger.Infof("Exporting state to file: %v", exportFilename)
 
 	allCells := map[string]*mxCell{}
 
@@ -132,11 +132,11 @@ func ExportState(state *model.State, exportFilename string) error {
 			needsKey := curNeeds.Type + model.IDSeparator + curNeeds.Name
 			sourceCell, ok := allCells[providesKey]
 			if !ok {
-				log.Printf("Warning: Provided resource not found: %v\n", providesKey)
+				logger.WithField(model.LogFieldResource, providesKey).Warnf("Provided resource not found")
 			} else {
 				targetCell, ok := allCells[needsKey]
 				if !ok {
-					log.Printf("Warning: Needed resource not found: %v\n", needsKey)
+					logger.WithField(model.LogFieldResource, needsKey).Warnf("Needed resource not found")
 				} else {
 					// Create the link between the two cells
 					linkedID := "diaglink" + fmt.Sprint(linkCount)
@@ -156,12 +156,12 @@ func ExportState(state *model.State, exportFilename string) error {
 	// Marshall the XML into an array of bytes, and save the results to the file system
 	output, err := xml.MarshalIndent(gm, " ", "  ")
 	if err != nil {
-		log.Printf("Unable to marshal the XML: %v\n", err)
+		logger.Debugf("Unable to marshal the XML: %v", err)
 		return err
 	}
 	err = saveFile(exportFilename, output)
 	if err != nil {
-		log.Printf("Error saving the file: %v\n", err)
+		logger.Debugf("Error saving the file: %v", err)
 		return err
 	}
 	return nil
@@ -1,8 +1,9 @@
 module rezolvr
 
-go 1.15
+go 1.16
 
 require (
 	github.com/stretchr/testify v1.6.1
+	github.com/xeipuuv/gojsonschema v1.2.0
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,173 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"rezolvr/model"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level determines which messages are written. Each level includes the ones before it.
+type Level int
+
+const (
+	// LevelError - only errors
+	LevelError Level = iota
+	// LevelWarn - errors and warnings
+	LevelWarn
+	// LevelInfo - progress messages; the default
+	LevelInfo
+	// LevelDebug - details of each component as it's loaded, resolved and transformed
+	LevelDebug
+	// LevelTrace - everything, including each formula and param
+	LevelTrace
+)
+
+var levelNames = []string{"error", "warn", "info", "debug", "trace"}
+
+func (l Level) String() string {
+	if l < LevelError || l > LevelTrace {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel converts a level name (error, warn, info, debug or trace) into a Level
+func ParseLevel(name string) (Level, error) {
+	for idx, curName := range levelNames {
+		if strings.EqualFold(name, curName) {
+			return Level(idx), nil
+		}
+	}
+	if strings.EqualFold(name, "warning") {
+		return LevelWarn, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level: %s (expected one of %s)", name, strings.Join(levelNames, ", "))
+}
+
+// FormatText writes one human-readable line per message
+const FormatText = "text"
+
+// FormatJSON writes one JSON object per message
+const FormatJSON = "json"
+
+type field struct {
+	key   string
+	value string
+}
+
+// Logger is the standard implementation of model.Logger
+type Logger struct {
+	out    io.Writer
+	level  Level
+	format string
+	fields []field
+	mu     *sync.Mutex
+	now    func() time.Time
+}
+
+// New creates a logger which writes messages at or below the given level
+func New(out io.Writer, level Level, format string) (*Logger, error) {
+	if format != FormatText && format != FormatJSON {
+		return nil, fmt.Errorf("unknown log format: %s (expected %s or %s)", format, FormatText, FormatJSON)
+	}
+	return &Logger{out: out, level: level, format: format, mu: &sync.Mutex{}, now: time.Now}, nil
+}
+
+// Level returns the most detailed level being written
+func (l *Logger) Level() Level {
+	return l.level
+}
+
+// Enabled determines if messages at a level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level <= l.level
+}
+
+// Errorf logs an error
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.write(LevelError, format, args...)
+}
+
+// Warnf logs a warning
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.write(LevelWarn, format, args...)
+}
+
+// Infof logs a progress message
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.write(LevelInfo, format, args...)
+}
+
+// Debugf logs a detailed message
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.write(LevelDebug, format, args...)
+}
+
+// Tracef logs a very detailed message
+func (l *Logger) Tracef(format string, args ...interface{}) {
+	l.write(LevelTrace, format, args...)
+}
+
+// WithField returns a logger which adds a field to every message. An existing field with the same key is replaced.
+func (l *Logger) WithField(key string, value string) model.Logger {
+	fields := make([]field, 0, len(l.fields)+1)
+	for _, curField := range l.fields {
+		if curField.key != key {
+			fields = append(fields, curField)
+		}
+	}
+	fields = append(fields, field{key: key, value: value})
+	child := *l
+	child.fields = fields
+	return &child
+}
+
+func (l *Logger) write(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	msg := strings.TrimRight(fmt.Sprintf(format, args...), "\n")
+	timestamp := l.now()
+
+	var line string
+	if l.format == FormatJSON {
+		entry := map[string]string{"time": timestamp.Format(time.RFC3339), "level": level.String(), "msg": msg}
+		for _, curField := range l.fields {
+			entry[curField.key] = curField.value
+		}
+		content, _ := json.Marshal(entry)
+		line = string(content) + "\n"
+	} else {
+		var sb strings.Builder
+		sb.WriteString(timestamp.Format("2006/01/02 15:04:05 "))
+		sb.WriteString(fmt.Sprintf("%-5s ", strings.ToUpper(level.String())))
+		sb.WriteString(msg)
+		for _, curField := range l.fields {
+			sb.WriteString(fmt.Sprintf(" %s=%q", curField.key, curField.value))
+		}
+		sb.WriteString("\n")
+		line = sb.String()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, line)
+}
@@ -0,0 +1,88 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"rezolvr/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogger(t *testing.T, level Level, format string) (*Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, level, format)
+	assert.Nil(t, err)
+	l.now = func() time.Time { return time.Date(2020, 10, 1, 12, 30, 0, 0, time.UTC) }
+	return l, buf
+}
+
+func Test_ParseLevel(t *testing.T) {
+	for _, curName := range []string{"error", "warn", "info", "debug", "trace"} {
+		level, err := ParseLevel(curName)
+		assert.Nil(t, err)
+		assert.Equal(t, curName, level.String())
+	}
+	level, err := ParseLevel("WARNING")
+	assert.Nil(t, err)
+	assert.Equal(t, LevelWarn, level)
+	_, err = ParseLevel("loud")
+	assert.NotNil(t, err)
+}
+
+func Test_LevelFiltering(t *testing.T) {
+	l, buf := newTestLogger(t, LevelWarn, FormatText)
+	l.Errorf("first")
+	l.Warnf("second")
+	l.Infof("third")
+	l.Debugf("fourth")
+	l.Tracef("fifth")
+	assert.Equal(t, "2020/10/01 12:30:00 ERROR first\n2020/10/01 12:30:00 WARN  second\n", buf.String())
+
+	_, err := New(buf, LevelInfo, "xml")
+	assert.NotNil(t, err)
+}
+
+func Test_Fields(t *testing.T) {
+	l, buf := newTestLogger(t, LevelTrace, FormatText)
+	var componentLogger model.Logger = l.WithField(model.LogFieldComponent, "resource.web.app:welcome")
+	componentLogger.WithField(model.LogFieldParam, "port").Tracef("Resolving formula\n")
+	componentLogger.Infof("Resolved")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, `2020/10/01 12:30:00 TRACE Resolving formula component="resource.web.app:welcome" param="port"`, lines[0])
+	assert.Equal(t, `2020/10/01 12:30:00 INFO  Resolved component="resource.web.app:welcome"`, lines[1])
+
+	// The parent logger is unchanged
+	buf.Reset()
+	l.Infof("plain")
+	assert.Equal(t, "2020/10/01 12:30:00 INFO  plain\n", buf.String())
+}
+
+func Test_JSONFormat(t *testing.T) {
+	l, buf := newTestLogger(t, LevelInfo, FormatJSON)
+	l.WithField(model.LogFieldResource, "storage.volume:dbvolume").Warnf("Needed resource not found: %d", 1)
+	entry := map[string]string{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, map[string]string{
+		"time":     "2020-10-01T12:30:00Z",
+		"level":    "warn",
+		"msg":      "Needed resource not found: 1",
+		"resource": "storage.volume:dbvolume",
+	}, entry)
+}
@@ -15,15 +15,25 @@ package main
 // limitations under the License.package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"os/signal"
 	"rezolvr/model"
+	"rezolvr/output"
+	"rezolvr/report"
+	"rezolvr/templates"
 	"rezolvr/utils"
+	"sort"
+	"strings"
 
-	xmlexport "rezolvr/exports/xmlexport"
 	"rezolvr/validation"
+
+	// Built-in drivers register themselves with the driver registry
+	_ "rezolvr/drivers/docker"
+	_ "rezolvr/drivers/helm"
+	_ "rezolvr/drivers/kube"
 )
 
 // Package-level variables
@@ -32,61 +42,130 @@ var allNewComponents []*model.Component
 var pluginDir string
 var driverName string
 var rezolvrPlugin model.RezolvrDriver
+var rezolvrPluginInfo *utils.DriverInfo
 var platformSettings map[string]*model.Platform
+var environmentName string
+var changedEnvCategories []string
+var logger = model.GetLogger()
+
+func loadEnvironment(environmentFiles []string) (*model.Component, error) {
+	// Combine every environment file into a single environment. Later files take precedence.
+	combinedEnv := &model.Component{}
+	combinedEnv.Provides = make(map[string]*model.Resource)
+	combinedEnv.Uses = make(map[string]*model.Resource)
+	for _, curEnvFile := range environmentFiles {
+		logger.Debugf("Attempting to load environment file: %s", curEnvFile)
+		content, err := utils.LoadFile(curEnvFile, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(content) == 0 {
+			logger.Warnf("The environment file is missing or empty: %s", curEnvFile)
+			continue
+		}
+		allEnvs, err := model.LoadComponents(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", curEnvFile, err)
+		}
+		for _, curEnv := range allEnvs {
+			if len(curEnv.Name) > 0 {
+				combinedEnv.Name = curEnv.Name
+			}
+			if len(curEnv.Driver) > 0 {
+				if len(combinedEnv.Driver) > 0 && combinedEnv.Driver != curEnv.Driver {
+					return nil, fmt.Errorf("conflicting drivers in the environment files: %s and %s", combinedEnv.Driver, curEnv.Driver)
+				}
+				combinedEnv.Driver = curEnv.Driver
+			}
+			for k, v := range curEnv.Provides {
+				combinedEnv.Provides[k] = v
+			}
+			for k, v := range curEnv.Uses {
+				combinedEnv.Uses[k] = v
+			}
+		}
+	}
+	return combinedEnv, nil
+}
 
 func loadRezolvrFiles(cliArgs *utils.CmdLineArgs) error {
-	// Load the component file(s) and the environment file
-	allNewComponents = make([]*model.Component, len(cliArgs.ComponentsToAdd))
-	for idx, curComponentFile := range cliArgs.ComponentsToAdd {
-		log.Printf("Attempting to load file: %s\n", curComponentFile)
+	// Load the component file(s) and the environment file(s)
+	// Component directories often contain the state and environment files, and the output, as well, so skip those
+	excludedFiles := append([]string{cliArgs.StateFile, cliArgs.StateFile + ".backup", cliArgs.ProjectFile, cliArgs.OutputDir}, cliArgs.EnvFiles...)
+	componentFiles, err := utils.ExpandComponentPaths(cliArgs.ComponentsToAdd, excludedFiles)
+	if err != nil {
+		return err
+	}
+	allNewComponents = make([]*model.Component, 0, len(componentFiles))
+	for _, curComponentFile := range componentFiles {
+		logger.Debugf("Attempting to load file: %s", curComponentFile)
 		content, err := utils.LoadFile(curComponentFile, true)
 		if err != nil {
-			log.Printf("Error loading file: %v\n", err)
 			return err
 		}
-		curComponent, err := model.LoadComponent(content)
+		// A single file may contain several components, separated by '---'
+		fileComponents, err := model.LoadComponents(content)
 		if err != nil {
-			log.Printf("Error loading component: %v\n", err)
-			return err
+			return fmt.Errorf("%s: %v", curComponentFile, err)
 		}
-		allNewComponents[idx] = curComponent
+		if len(fileComponents) == 0 {
+			logger.Warnf("No components found in file: %s", curComponentFile)
+			if cliArgs.Strict {
+				return fmt.Errorf("strict mode: no components found in file: %s", curComponentFile)
+			}
+		}
+		allNewComponents = append(allNewComponents, fileComponents...)
 	}
 
-	environmentFile := cliArgs.EnvFile
-	content, err := utils.LoadFile(environmentFile, false)
-	initialEnv, err := model.LoadComponent(content)
+	initialEnv, err := loadEnvironment(cliArgs.EnvFiles)
 	if err != nil {
-		log.Printf("Error loading environment details: %v", err)
 		return err
 	}
 	driverName = initialEnv.Driver
+	environmentName = initialEnv.Name
+	if len(cliArgs.Driver) > 0 {
+		driverName = cliArgs.Driver
+	}
 
-	content, err = utils.LoadFile(cliArgs.StateFile, false)
+	content, err := utils.LoadFile(cliArgs.StateFile, false)
 	if err != nil {
 		return err
 	}
 	state, err = model.LoadState(content)
 	if err != nil {
-		log.Printf("Error loading state: %v", err)
 		return err
 	}
 
 	// Combine the environment properties with the existing state environment properties
 	// New environment properties take precedent over existing state envrionment properties
-	stateProps := state.Components["environment.properties"].Provides
-	for envKey, envVal := range initialEnv.Provides {
-		stateProps[envKey] = envVal
-	}
-
-	// Attempt to load a plugin to handle the transformation
-	driverFullName := pluginDir + driverName + "/plugin" + driverName + ".so"
-	log.Printf("Attempting to load plugin: %s\n", driverFullName)
-	rezolvrPlugin, err = utils.LoadPlugin(driverFullName)
-	if err != nil || rezolvrPlugin == nil {
-		msg := fmt.Sprintf("Unsuitable driver found: *%v*\n", driverName)
-		err = errors.New(msg)
-		return err
+	// Any categories which differ from the state must be re-resolved by the components which need them
+	var envChanges []validation.EnvironmentChange
+	if cliArgs.SyncEnvironment {
+		// The environment file is authoritative; anything missing from it is dropped from the state
+		envChanges = validation.SyncEnvironmentProperties(state, initialEnv.Provides)
+		if len(envChanges) > 0 {
+			logger.Infof("Environment properties synchronized with the environment file:")
+			for _, curChange := range envChanges {
+				logger.Infof("  %v", curChange)
+				cmdReport.EnvironmentChanges = append(cmdReport.EnvironmentChanges, curChange.String())
+			}
+		}
+	} else {
+		stateProps := state.Components["environment.properties"].Provides
+		envChanges = validation.DiffEnvironmentProperties(stateProps, initialEnv.Provides)
+		staleProps := validation.GetStaleEnvironmentProperties(stateProps, initialEnv.Provides)
+		for _, curStale := range staleProps {
+			logger.WithField(model.LogFieldResource, curStale).Warnf("Environment property exists in the state, but not in the environment file. Use --sync-env to remove it.")
+		}
+		if cliArgs.Strict && len(staleProps) > 0 {
+			return fmt.Errorf("strict mode: environment properties exist in the state, but not in the environment file: %s", strings.Join(staleProps, ", "))
+		}
+		validation.MergeEnvironmentProperties(state, initialEnv.Provides)
+		for _, curChange := range envChanges {
+			cmdReport.EnvironmentChanges = append(cmdReport.EnvironmentChanges, curChange.String())
+		}
 	}
+	changedEnvCategories = validation.GetChangedEnvironmentCategories(envChanges, cliArgs.SyncEnvironment)
 
 	// Load platform-specific settings
 	platformSettings = make(map[string]*model.Platform)
@@ -99,12 +178,39 @@ func loadRezolvrFiles(cliArgs *utils.CmdLineArgs) error {
 	return nil
 }
 
-func applyUpdatedComponents(cliArgs *utils.CmdLineArgs) error {
+func loadDriver() error {
+	// Attempt to load a driver to handle the transformation
+	var err error
+	rezolvrPlugin, rezolvrPluginInfo, err = utils.LoadDriver(pluginDir, driverName)
+	if err != nil {
+		return report.WithCode(report.CodeDriver, fmt.Errorf("unsuitable driver found: *%v*: %v", driverName, err))
+	}
+	logger.Debugf("Using the %s driver, version %s (%s): %s", driverName, rezolvrPlugin.Version(), rezolvrPluginInfo.Kind, rezolvrPluginInfo.Location)
+	rezolvrPlugin.SetLogger(logger.WithField("driver", driverName))
+	if apiVersion := rezolvrPlugin.Capabilities().APIVersion; apiVersion != model.DriverAPIVersion {
+		closeDriver()
+		return report.WithCode(report.CodeDriver, fmt.Errorf("the %s driver implements version %d of the driver interface, but rezolvr requires version %d",
+			driverName, apiVersion, model.DriverAPIVersion))
+	}
+	return nil
+}
+
+// closeDriver - stop the driver's process, if it has one
+func closeDriver() {
+	if closer, ok := rezolvrPlugin.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Warnf("%v", err)
+		}
+	}
+	rezolvrPlugin = nil
+}
+
+func getComponentsToResolve(cliArgs *utils.CmdLineArgs) (map[string]*model.Component, error) {
 
 	// Ensure the state of previously-defined components / resources is valid
 	err := validation.ValidateState(state)
 	if err != nil {
-		return err
+		return nil, report.WithCode(report.CodeState, err)
 	}
 
 	// If there are components to remove, remove them from the state
@@ -133,78 +239,256 @@ func applyUpdatedComponents(cliArgs *utils.CmdLineArgs) error {
 		// Check to see if any existing components are dependent upon the new component
 		// This needs to be recursive to get a complete list of all components which must be re-resolved
 		componentsNeedingUpdate := map[string]*model.Component{}
+		if len(changedEnvCategories) > 0 {
+			logger.Debugf("Locating components impacted by changed environment properties...")
+			for k, v := range validation.GetComponentsNeedingResources(state, changedEnvCategories) {
+				componentsNeedingUpdate[k] = v
+			}
+		}
 		for _, v := range allNewComponents {
 			componentID := v.Type + model.IDSeparator + v.Name
 			componentsNeedingUpdate[componentID] = v
 		}
 
-		log.Println("Locating impacted components which must be resolved...")
+		logger.Debugf("Locating impacted components which must be resolved...")
 		componentsToResolve = validation.GetImpactedComponents(state, componentsNeedingUpdate)
 	}
+	return componentsToResolve, nil
+}
+
+// resolveComponents resolves the given components. When drift is reported (by refresh), the differences from the values
+// currently stored in the state are listed; new components have no stored values, so all of their params are listed.
+func resolveComponents(componentsToResolve map[string]*model.Component, withDrift bool) (map[string]*model.Component, error) {
+	var storedValues map[validation.ParamLocation]string
+	if withDrift {
+		storedComponents := map[string]*model.Component{}
+		for k := range componentsToResolve {
+			if storedComponent, ok := state.Components[k]; ok {
+				storedComponents[k] = storedComponent
+			}
+		}
+		storedValues = validation.SnapshotParamValues(storedComponents)
+	}
 
-	log.Println("Resolving components...")
+	logger.Infof("Resolving components...")
 	allUpdatedComponents, err := utils.ResolveAllComponents(state, componentsToResolve)
+	if err != nil {
+		return nil, report.WithCode(report.CodeResolve, err)
+	}
+	secretValues := model.SecretValues(state.Components)
+	for k, v := range model.SecretValues(allUpdatedComponents) {
+		secretValues[k] = v
+	}
+	cmdReport.AddComponents(allUpdatedComponents, secretValues)
+	if withDrift {
+		reportDrift(validation.GetParamDrift(storedValues, validation.SnapshotParamValues(allUpdatedComponents), secretValues))
+	}
+	return allUpdatedComponents, nil
+}
+
+func applyUpdatedComponents(cliArgs *utils.CmdLineArgs) error {
+	componentsToResolve, err := getComponentsToResolve(cliArgs)
 	if err != nil {
 		return err
 	}
-	// Transform the components into output files
-	log.Println("Transforming components...")
-	rezolvrPlugin.TransformComponents(allUpdatedComponents, state, pluginDir+driverName+"/", cliArgs.OutputDir, platformSettings)
+	allUpdatedComponents, err := resolveComponents(componentsToResolve, false)
+	if err != nil {
+		return err
+	}
+	return transformAndSaveComponents(cliArgs, allUpdatedComponents)
+}
 
-	// Add the updated components to the state
-	log.Println("Adding updated components to the state of the system...")
-	for k, v := range allUpdatedComponents {
-		state.Components[k] = v
+func refreshAllComponents(cliArgs *utils.CmdLineArgs) error {
+
+	// Ensure the state of previously-defined components / resources is valid
+	err := validation.ValidateState(state)
+	if err != nil {
+		return report.WithCode(report.CodeState, err)
 	}
 
-	// Persist the updated state
-	log.Println("Saving the system state...")
-	content, err := model.PrepStateForPersistence(state)
-	err = utils.SaveFile(cliArgs.StateFile, content)
-	return nil
+	// Every component in the state is re-resolved from scratch
+	componentsToResolve := map[string]*model.Component{}
+	for curExistingComponentID, curExistingComponent := range state.Components {
+		if !(curExistingComponentID == "environment.properties") {
+			utils.MarkComponentResolvedStatus(curExistingComponent, utils.UNRESOLVED)
+			componentsToResolve[curExistingComponentID] = curExistingComponent
+		}
+	}
+	allUpdatedComponents, err := resolveComponents(componentsToResolve, true)
+	if err != nil {
+		return err
+	}
+	return transformAndSaveComponents(cliArgs, allUpdatedComponents)
 }
 
-func main() {
-	log.Println("rezolvr version: 0.0.1")
+func whatIfUpdatedComponents(cliArgs *utils.CmdLineArgs) error {
+	componentsToResolve, err := getComponentsToResolve(cliArgs)
+	if err != nil {
+		return err
+	}
+	allUpdatedComponents, err := resolveComponents(componentsToResolve, false)
+	if err != nil {
+		return err
+	}
+	logger.Infof("The following components would be updated (%d):", len(allUpdatedComponents))
+	for k := range allUpdatedComponents {
+		logger.Infof("  %s", k)
+	}
+	logger.Infof("No output was generated, and the state was not saved")
+	return nil
+}
 
-	cliArgs, err := utils.ParseArgs(os.Args)
+// validateComponents resolves the components which apply would resolve: the new components, those which need changed
+// environment properties, and those they impact (or every component, when some are deleted)
+func validateComponents(cliArgs *utils.CmdLineArgs) error {
+	componentsToResolve, err := getComponentsToResolve(cliArgs)
 	if err != nil {
-		log.Println(err)
-		log.Fatal("Usage: rezolvr apply -a/-r <component file(s)> -e <environment file> -s <state file>")
+		return err
 	}
-	var ok bool
-	pluginDir, ok = os.LookupEnv("REZOLVR_PLUGINDIR")
-	if !ok {
-		homeDir := os.Getenv("HOME")
-		pluginDir = homeDir + "/.rezolvr/plugins/"
+	_, err = resolveComponents(componentsToResolve, false)
+	if err != nil {
+		return err
 	}
-	log.Printf("Plugin directory: %s\n", pluginDir)
+	logger.Infof("The changed components, and those they impact, were successfully resolved")
+	return nil
+}
 
-	// Only 'apply' and 'export' are supported for now
-	if cliArgs.Command == "export" {
-		content, err := utils.LoadFile(cliArgs.StateFile, false)
-		if err != nil {
-			log.Fatal("Error loading state file")
-		}
-		state, err := model.LoadState(content)
-		if err != nil {
-			msg := fmt.Sprintf("Error loading state: %v", err)
-			log.Fatal(msg)
-		}
-		err = xmlexport.ExportState(state, cliArgs.ExportFile)
-		if err != nil {
-			fmt.Printf("Error exporting state: %v\n", err)
-		}
-	} else if cliArgs.Command == "apply" {
-		err = loadRezolvrFiles(cliArgs)
-		if err == nil {
-			err = applyUpdatedComponents(cliArgs)
+// reportDrift logs a summary of the drift, and the details at debug level. The values of secrets are masked, both in
+// the log and in the report.
+func reportDrift(drift []validation.ParamDrift) {
+	if len(drift) > 0 {
+		logger.Infof("Drift detected between the stored state and the resolved components (%d params). Use --log-level debug to list them.", len(drift))
+		for _, curDrift := range drift {
+			logger.Debugf("  %v", curDrift)
+			oldValue, newValue := curDrift.DisplayValues()
+			cmdReport.Changes = append(cmdReport.Changes, report.Change{Component: curDrift.ComponentID, Section: curDrift.Section,
+				Resource: curDrift.ResourceID, Param: curDrift.Param, OldValue: oldValue, NewValue: newValue})
 		}
+	} else {
+		logger.Infof("No drift detected between the stored state and the resolved components")
+	}
+}
+
+// getSupportedResourceTypes - the resource types the driver can render: those it reports in its capabilities, along with
+// any which have a template in the project's or the user's template directories
+func getSupportedResourceTypes(driverDir string) ([]string, error) {
+	supportedTypes := append([]string{}, rezolvrPlugin.Capabilities().ResourceTypes...)
+	overrides, err := templates.NewLoader(driverName, driverDir, nil).List()
+	if err != nil {
+		return nil, err
+	}
+	for _, curTemplate := range overrides {
+		supportedTypes = append(supportedTypes, curTemplate.Name)
+	}
+	return supportedTypes, nil
+}
+
+// checkDriverCoverage - fail when any provided resource would produce no output, because the driver can't render its type.
+// External resources, and resources with skipOutput in their platform settings, are excused.
+func checkDriverCoverage(request *model.TransformRequest) error {
+	supportedTypes, err := getSupportedResourceTypes(request.PluginDir)
+	if err != nil {
+		return report.WithCode(report.CodeDriver, err)
+	}
+	allComponents := make(map[string]*model.Component)
+	for k, v := range state.Components {
+		allComponents[k] = v
+	}
+	for k, v := range request.UpdatedComponents {
+		allComponents[k] = v
+	}
+	unsupported := validation.GetUnsupportedResources(allComponents, supportedTypes, platformSettings)
+	if len(unsupported) == 0 {
+		return nil
+	}
+	logger.Errorf("The %s driver can't render the following resources, so they would produce no output (%d):", driverName, len(unsupported))
+	unsupportedTypes := make(map[string]bool)
+	for _, curUnsupported := range unsupported {
+		logger.Errorf("  %v", curUnsupported)
+		unsupportedTypes[curUnsupported.Type] = true
+	}
+	typeNames := make([]string, 0, len(unsupportedTypes))
+	for k := range unsupportedTypes {
+		typeNames = append(typeNames, k)
+	}
+	sort.Strings(typeNames)
+	return report.WithCode(report.CodeDriver, fmt.Errorf("the %s driver does not support these resource types: %s. Add a template to %s, "+
+		"or set %s (or %s) to true in the resource's platform settings",
+		driverName, strings.Join(typeNames, ", "), templates.ProjectTemplateDir(driverName),
+		model.PlatformParamSkipOutput, model.PlatformParamIsExternal))
+}
+
+// writeArtifacts - write the driver's artifacts to the output directory, a tarball or stdout
+func writeArtifacts(cliArgs *utils.CmdLineArgs, artifacts []model.Artifact) error {
+	var writer output.Writer
+	if cliArgs.OutputStdout {
+		writer = output.NewStreamWriter(os.Stdout)
+	} else if cliArgs.OutputTar == "-" {
+		writer = output.NewTarWriter("", os.Stdout, nil)
+	} else if len(cliArgs.OutputTar) > 0 {
+		tarFile, err := os.Create(cliArgs.OutputTar)
 		if err != nil {
-			log.Fatalf("Error encountered: %v\n", err)
+			return err
 		}
+		writer = output.NewTarWriter(cliArgs.OutputTar, tarFile, tarFile)
 	} else {
-		log.Fatal("Usage: only the 'apply' and 'export' commands are supported")
+		writer = output.NewDirWriter(cliArgs.OutputDir)
+	}
+	for _, curArtifact := range artifacts {
+		logger.Debugf("Writing %s: %s", curArtifact.Format, curArtifact.Path)
+		if err := writer.Write(curArtifact); err != nil {
+			writer.Close()
+			return err
+		}
 	}
-	log.Println("Rezolvr completed")
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	cmdReport.Files = append(cmdReport.Files, writer.Files()...)
+	return nil
+}
+
+func transformAndSaveComponents(cliArgs *utils.CmdLineArgs, allUpdatedComponents map[string]*model.Component) error {
+	// Transform the components into output files. The state is only saved when the driver succeeds.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	request := &model.TransformRequest{UpdatedComponents: allUpdatedComponents, State: state, PluginDir: pluginDir + driverName + "/",
+		PlatformSettings: platformSettings, Environment: environmentName}
+	if err := checkDriverCoverage(request); err != nil {
+		return err
+	}
+	logger.Debugf("Validating the components with the %s driver...", driverName)
+	if err := rezolvrPlugin.Validate(ctx, request); err != nil {
+		return report.WithCode(report.CodeDriver, fmt.Errorf("the %s driver rejected the components; the state was not saved: %v", driverName, err))
+	}
+	logger.Infof("Transforming components...")
+	result, err := rezolvrPlugin.Transform(ctx, request)
+	if err != nil {
+		return report.WithCode(report.CodeDriver, fmt.Errorf("the %s driver was unable to generate the output; the state was not saved: %v", driverName, err))
+	}
+	if err := writeArtifacts(cliArgs, result.Artifacts); err != nil {
+		return report.WithCode(report.CodeSave, fmt.Errorf("unable to write the generated files; the state was not saved: %v", err))
+	}
+
+	// Add the updated components to the state
+	logger.Debugf("Adding updated components to the state of the system...")
+	for k, v := range allUpdatedComponents {
+		state.Components[k] = v
+	}
+
+	// Persist the updated state
+	logger.Infof("Saving the system state...")
+	content, err := model.PrepStateForPersistence(state)
+	if err == nil {
+		err = utils.SaveFile(cliArgs.StateFile, content)
+	}
+	if err != nil {
+		return report.WithCode(report.CodeSave, err)
+	}
+	cmdReport.Files = append(cmdReport.Files, cliArgs.StateFile)
+	return nil
+}
+
+func main() {
+	os.Exit(newRootCommand().Execute(os.Args[1:], os.Stdout, os.Stderr))
 }
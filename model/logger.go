@@ -0,0 +1,43 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+// nopLogger discards every message. It's used until a logger has been configured.
+type nopLogger struct{}
+
+func (nl nopLogger) Errorf(format string, args ...interface{}) {}
+func (nl nopLogger) Warnf(format string, args ...interface{})  {}
+func (nl nopLogger) Infof(format string, args ...interface{})  {}
+func (nl nopLogger) Debugf(format string, args ...interface{}) {}
+func (nl nopLogger) Tracef(format string, args ...interface{}) {}
+
+func (nl nopLogger) WithField(key string, value string) Logger {
+	return nl
+}
+
+var logger Logger = nopLogger{}
+
+// SetLogger configures the logger used by the model, resolver and validation packages. nil discards all messages.
+func SetLogger(newLogger Logger) {
+	if newLogger == nil {
+		newLogger = nopLogger{}
+	}
+	logger = newLogger
+}
+
+// GetLogger returns the configured logger
+func GetLogger() Logger {
+	return logger
+}
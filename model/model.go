@@ -15,7 +15,9 @@
 package model
 
 import (
-	"log"
+	"bytes"
+	"fmt"
+	"io"
 
 	"gopkg.in/yaml.v2"
 )
@@ -42,6 +44,8 @@ type persistedComponent struct {
 	Provides    []persistedResource
 	Uses        []persistedResource
 	Needs       []persistedResource
+	// DeploymentHints are stored as-is, within the state
+	DeploymentHints *DeploymentHints `yaml:"deploymentHints,omitempty"`
 }
 
 type persistedState struct {
@@ -54,7 +58,7 @@ func LoadState(content []byte) (*State, error) {
 	//content, err := loadFile(fileName, false)
 	if len(content) < 2 {
 		// No existing state was found; create a new empty state
-		log.Println("The state is empty. Creating a brand new state model...")
+		logger.Infof("The state is empty. Creating a brand new state model...")
 		s := State{}
 		components := make(map[string]*Component)
 		c := Component{Name: "environment.properties"}
@@ -89,6 +93,12 @@ func PrepStateForPersistence(state *State) ([]byte, error) {
 	return content, nil
 }
 
+// PrepComponentForPersistence converts a single component into the same YAML format used by the state file
+func PrepComponentForPersistence(component *Component) ([]byte, error) {
+	pc := flattenComponent(component)
+	return yaml.Marshal(pc)
+}
+
 func transformState(persistedState *persistedState) (*State, error) {
 	state := &State{}
 	state.Components = make(map[string]*Component)
@@ -172,23 +182,27 @@ func flattenState(state *State) (*persistedState, error) {
 	ps.Components = make([]persistedComponent, totalComponents)
 	for compName, curComp := range state.Components {
 		if compName != "environment.properties" {
-			pc := persistedComponent{}
-			pc.Name = curComp.Name
-			pc.Type = curComp.Type
-			pc.Driver = curComp.Driver
-			pc.Description = curComp.Description
-
-			pc.Needs = *flattenParams(curComp.Needs)
-			pc.Uses = *flattenParams(curComp.Uses)
-			pc.Provides = *flattenParams(curComp.Provides)
-
-			ps.Components[componentCount] = pc
+			ps.Components[componentCount] = *flattenComponent(curComp)
 			componentCount++
 		}
 	}
 	return &ps, nil
 }
 
+func flattenComponent(curComp *Component) *persistedComponent {
+	pc := persistedComponent{}
+	pc.Name = curComp.Name
+	pc.Type = curComp.Type
+	pc.Driver = curComp.Driver
+	pc.Description = curComp.Description
+
+	pc.Needs = *flattenParams(curComp.Needs)
+	pc.Uses = *flattenParams(curComp.Uses)
+	pc.Provides = *flattenParams(curComp.Provides)
+	pc.DeploymentHints = curComp.DeploymentHints
+	return &pc
+}
+
 // LoadComponent - given the contents of a YAML file, load the file and convert it into a component
 func LoadComponent(content []byte) (*Component, error) {
 	if content == nil {
@@ -209,6 +223,38 @@ func LoadComponent(content []byte) (*Component, error) {
 	return component, nil
 }
 
+// isEmpty - determine if a document contained nothing but comments or whitespace
+func (pc *persistedComponent) isEmpty() bool {
+	return len(pc.Name) == 0 && len(pc.Type) == 0 && len(pc.Provides) == 0 && len(pc.Uses) == 0 && len(pc.Needs) == 0
+}
+
+// LoadComponents - given the contents of a YAML file, load every '---' separated document as its own component.
+// Empty documents are skipped. Errors identify the (1-based) document index.
+func LoadComponents(content []byte) ([]*Component, error) {
+	components := make([]*Component, 0)
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for docIdx := 1; ; docIdx++ {
+		persistedComponent := &persistedComponent{}
+		err := decoder.Decode(persistedComponent)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", docIdx, err)
+		}
+		if persistedComponent.isEmpty() {
+			continue
+		}
+
+		component, err := transformPersistentComponent(persistedComponent)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", docIdx, err)
+		}
+		components = append(components, component)
+	}
+	return components, nil
+}
+
 func transformNvParamsToParams(nvParams []NvParam) []Param {
 	params := make([]Param, len(nvParams))
 	for idx, nvParam := range nvParams {
@@ -255,6 +301,7 @@ func transformPersistentComponent(pComponent *persistedComponent) (*Component, e
 	component.Provides = transformPersistentResource(&pComponent.Provides)
 	component.Uses = transformPersistentResource(&pComponent.Uses)
 	component.Needs = transformPersistentResource(&pComponent.Needs)
+	component.DeploymentHints = pComponent.DeploymentHints
 
 	return component, nil
 }
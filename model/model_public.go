@@ -14,13 +14,81 @@
 
 package model
 
+import "context"
+
+// DriverAPIVersion is the version of the RezolvrDriver interface. It's incremented whenever the interface changes in an
+// incompatible way, and drivers report the version they implement in their capabilities.
+const DriverAPIVersion = 3
+
 // RezolvrDriver is the interface plugins use.
 type RezolvrDriver interface {
-	PrintMessage()
-	TransformComponents(updatedComponents map[string]*Component, state *State,
-		pluginDir string, outputDir string, platformSettings map[string]*Platform)
+	// Name is the name used to select the driver, e.g. in an environment file's 'driver' field
+	Name() string
+	// Version is the version of the driver itself
+	Version() string
+	// SetLogger is called before any other method, so that the plugin's messages honour rezolvr's log level and format
+	SetLogger(logger Logger)
+	// Capabilities describes what the driver supports
+	Capabilities() Capabilities
+	// Validate performs pre-flight checks on a request, before any output is generated
+	Validate(ctx context.Context, request *TransformRequest) error
+	// Transform generates the output for a request, and returns the artifacts it produced. Drivers don't write any
+	// files themselves; rezolvr writes the artifacts. When an error is returned, rezolvr doesn't save the state.
+	Transform(ctx context.Context, request *TransformRequest) (*TransformResult, error)
+}
+
+// Capabilities describes what a driver supports
+type Capabilities struct {
+	APIVersion    int      `json:"apiVersion"`
+	ResourceTypes []string `json:"resourceTypes"`
+	OutputFormats []string `json:"outputFormats"`
+}
+
+// TransformRequest carries everything a driver needs to generate its output. The updated components take precedence
+// over the components within the state.
+type TransformRequest struct {
+	UpdatedComponents map[string]*Component `json:"updatedComponents"`
+	State             *State                `json:"state"`
+	PluginDir         string                `json:"pluginDir"`
+	PlatformSettings  map[string]*Platform  `json:"platformSettings"`
+	// Environment - the name of the environment (from the environment file), which may be empty
+	Environment string `json:"environment,omitempty"`
 }
 
+// TransformResult lists the artifacts produced by a driver
+type TransformResult struct {
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// Artifact is a single file produced by a driver. The path is relative to the output (e.g. the output directory),
+// and may contain subdirectories. A zero mode means 0644.
+type Artifact struct {
+	Path    string `json:"path"`
+	Format  string `json:"format"`
+	Content []byte `json:"content"`
+	Mode    uint32 `json:"mode,omitempty"`
+}
+
+// Logger is the leveled logger shared by rezolvr and its plugins. Messages are written only when the logger's
+// level allows it, so detailed (debug / trace) messages are cheap to leave in place.
+type Logger interface {
+	Errorf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Tracef(format string, args ...interface{})
+
+	// WithField returns a logger which adds a field (e.g. the component being resolved) to every message
+	WithField(key string, value string) Logger
+}
+
+// Field names used when attaching context to log messages
+const (
+	LogFieldComponent = "component"
+	LogFieldResource  = "resource"
+	LogFieldParam     = "param"
+)
+
 // IDSeparator is used to concatenate a resource's type and name
 const IDSeparator = ":"
 
@@ -50,6 +118,27 @@ type Platform struct {
 	Params map[string]*Param
 }
 
+// Platform settings params which excuse a resource from producing output
+const (
+	PlatformParamIsExternal = "isExternal"
+	PlatformParamSkipOutput = "skipOutput"
+)
+
+// OutputExcusedBy - the platform setting (isExternal or skipOutput) which says that a resource doesn't need any output,
+// either because it's an external resource or because it's been explicitly opted out. Empty when it needs output.
+func OutputExcusedBy(resourceName string, platformSettings map[string]*Platform) string {
+	resourceSettings := platformSettings[resourceName]
+	if resourceSettings == nil {
+		return ""
+	}
+	for _, curParamName := range []string{PlatformParamIsExternal, PlatformParamSkipOutput} {
+		if curParam := resourceSettings.Params[curParamName]; curParam != nil && curParam.Value == "true" {
+			return curParamName
+		}
+	}
+	return ""
+}
+
 // Candidate terms:
 // Larger "things": Package, Group, Pack, Bundle, Assortment, Bale, Component, Group, Parcel
 // Smaller "things": Capability, Feature, Service, Component, Joule, Resource
@@ -63,12 +152,56 @@ type Component struct {
 	Provides              map[string]*Resource
 	Uses                  map[string]*Resource
 	Needs                 map[string]*Resource
+	DeploymentHints       *DeploymentHints
 	RezolvrStatus         int
 	NeedsRezolvrStatus    int
 	UsesRezolvrStatus     int
 	ProvidesRezolvrStatus int
 }
 
+// DeploymentHints describe how a component would like to be deployed, independently of the platform. Drivers map them
+// to platform-specific settings, and ignore those they don't support. Platform settings take precedence.
+type DeploymentHints struct {
+	// Instances - the number of instances to run
+	Instances int `yaml:"instances,omitempty" json:"instances,omitempty"`
+	// MinInstances and MaxInstances - the range within which the number of instances is scaled. Scaling is enabled by
+	// MaxInstances.
+	MinInstances int `yaml:"minInstances,omitempty" json:"minInstances,omitempty"`
+	MaxInstances int `yaml:"maxInstances,omitempty" json:"maxInstances,omitempty"`
+	// TargetCPUUtilization - the average CPU utilization (percent) which scaling aims for
+	TargetCPUUtilization int `yaml:"targetCPUUtilization,omitempty" json:"targetCPUUtilization,omitempty"`
+	// MinAvailable - the number of instances which must remain available during maintenance (e.g. node upgrades)
+	MinAvailable int `yaml:"minAvailable,omitempty" json:"minAvailable,omitempty"`
+	// AvailabilityZones - the number of zones the instances should be spread across
+	AvailabilityZones int `yaml:"availabilityZones,omitempty" json:"availabilityZones,omitempty"`
+	// MinRAM / MaxRAM and MinCPU / MaxCPU - the memory (e.g. 512Mi or 4GB) and CPU (e.g. 500m or 2) each instance
+	// requests, and is limited to
+	MinRAM string `yaml:"minRam,omitempty" json:"minRam,omitempty"`
+	MaxRAM string `yaml:"maxRam,omitempty" json:"maxRam,omitempty"`
+	MinCPU string `yaml:"minCpu,omitempty" json:"minCpu,omitempty"`
+	MaxCPU string `yaml:"maxCpu,omitempty" json:"maxCpu,omitempty"`
+	// HealthCheck - how to tell whether an instance is healthy, and ready for requests
+	HealthCheck *HealthCheck `yaml:"healthCheck,omitempty" json:"healthCheck,omitempty"`
+}
+
+// HealthCheck - an HTTP request (when Path is set) or a TCP connection, which succeeds while an instance is healthy
+type HealthCheck struct {
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Port - defaults to the port the component provides
+	Port                int `yaml:"port,omitempty" json:"port,omitempty"`
+	InitialDelaySeconds int `yaml:"initialDelaySeconds,omitempty" json:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int `yaml:"periodSeconds,omitempty" json:"periodSeconds,omitempty"`
+	FailureThreshold    int `yaml:"failureThreshold,omitempty" json:"failureThreshold,omitempty"`
+}
+
+// ID returns the key used for the component within the state, e.g. resource.web.app:welcome
+func (c *Component) ID() string {
+	if len(c.Type) == 0 {
+		return c.Name
+	}
+	return c.Type + IDSeparator + c.Name
+}
+
 // State manages the overall state of the system
 type State struct {
 	Components map[string]*Component
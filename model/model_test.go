@@ -197,3 +197,58 @@ func Test_LoadComponent(t *testing.T) {
 	myParm := loadedComponent.Provides["service.web.app:welcomeappservice"].Params["port"].Value
 	assert.Equal(t, "3000", myParm)
 }
+
+func Test_LoadComponents(t *testing.T) {
+	content := []byte(getSampleComponent() + `
+---
+# Intentionally empty
+---
+name: imageRegistry
+type: resource.container.registry
+provides:
+  - type: service.container.registry
+    name: imageRegistry
+`)
+	components, err := LoadComponents(content)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(components))
+	assert.Equal(t, "welcome", components[0].Name)
+	assert.Equal(t, "imageRegistry", components[1].Name)
+	_, ok := components[1].Provides["service.container.registry:imageRegistry"]
+	assert.True(t, ok)
+
+	// Errors identify the offending document
+	_, err = LoadComponents([]byte(getSampleComponent() + "\n---\nname: [\n"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "document 2")
+}
+
+func Test_DeploymentHints(t *testing.T) {
+	content := []byte(getSampleComponent() + `
+deploymentHints:
+  instances: 2
+  availabilityZones: 2
+  minRam: 4GB
+  healthCheck:
+    path: /health`)
+	component, err := LoadComponent(content)
+	assert.Nil(t, err)
+	assert.Equal(t, &DeploymentHints{Instances: 2, AvailabilityZones: 2, MinRAM: "4GB", HealthCheck: &HealthCheck{Path: "/health"}},
+		component.DeploymentHints)
+
+	// The hints are kept within the state
+	state, err := LoadState(nil)
+	assert.Nil(t, err)
+	state.Components[component.ID()] = component
+	persisted, err := PrepStateForPersistence(state)
+	assert.Nil(t, err)
+	reloaded, err := LoadState(persisted)
+	assert.Nil(t, err)
+	assert.Equal(t, component.DeploymentHints, reloaded.Components[component.ID()].DeploymentHints)
+
+	// Components without hints don't persist an empty section
+	component.DeploymentHints = nil
+	persisted, err = PrepComponentForPersistence(component)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(persisted), "deploymentHints")
+}
@@ -0,0 +1,63 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaskedValue replaces the values of secrets when they're displayed
+const MaskedValue = "******"
+
+// MaskValue - the value to display for a secret. An empty value isn't masked.
+func MaskValue(value string) string {
+	if len(value) == 0 {
+		return value
+	}
+	return MaskedValue
+}
+
+// isSecretResource - whether a resource ID (e.g. environment.secret:dbcreds, or secret for a use) names a secret
+func isSecretResource(resourceID string) bool {
+	resourceType := strings.SplitN(resourceID, IDSeparator, 2)[0]
+	return resourceType == "environment.secret" || resourceType == "secret"
+}
+
+// secretParamName - the names of params which hold secrets, even outside a secret (e.g. db_password, DB_PW, apiKey)
+var secretParamName = regexp.MustCompile(`(?i)(passw|passwd|pwd|secret|token|credential|api_?key|private_?key|(^|_)pw($|_))`)
+
+// IsSecretParam - whether a param belongs to a secret, or its name says that it holds one
+func IsSecretParam(resourceID string, paramName string) bool {
+	return isSecretResource(resourceID) || secretParamName.MatchString(paramName)
+}
+
+// SecretValues - the values of the params of the components which are secrets: those of the environment.secret and
+// secret resources, and those whose name says that they hold one
+func SecretValues(components map[string]*Component) map[string]bool {
+	results := make(map[string]bool)
+	for _, curComponent := range components {
+		for _, resources := range []map[string]*Resource{curComponent.Needs, curComponent.Uses, curComponent.Provides} {
+			for resID, curResource := range resources {
+				for paramName, curParam := range curResource.Params {
+					if len(curParam.Value) > 0 && IsSecretParam(resID, paramName) {
+						results[curParam.Value] = true
+					}
+				}
+			}
+		}
+	}
+	return results
+}
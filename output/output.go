@@ -0,0 +1,192 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package output writes the artifacts produced by a driver: to a directory, to a tarball, or to stdout.
+// Drivers only name their artifacts; the paths are always relative to the output, and may not escape it.
+package output
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"rezolvr/model"
+	"strings"
+	"time"
+)
+
+// DefaultMode is used for artifacts which don't specify a file mode
+const DefaultMode = 0644
+
+// Writer writes artifacts to a destination. Close must be called once every artifact has been written.
+type Writer interface {
+	Write(artifact model.Artifact) error
+	Close() error
+	// Files lists the files which were written, for the command's report
+	Files() []string
+}
+
+// CleanPath checks that an artifact's path is relative, and stays within the output once any '..' elements are
+// resolved. The cleaned path uses forward slashes.
+func CleanPath(artifactPath string) (string, error) {
+	slashPath := filepath.ToSlash(artifactPath)
+	if len(slashPath) == 0 || path.IsAbs(slashPath) || filepath.IsAbs(artifactPath) || filepath.VolumeName(artifactPath) != "" {
+		return "", fmt.Errorf("invalid artifact path: '%s' (artifact paths must be relative to the output)", artifactPath)
+	}
+	cleaned := path.Clean(slashPath)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid artifact path: '%s' (artifacts may not be written outside of the output)", artifactPath)
+	}
+	return cleaned, nil
+}
+
+func artifactMode(artifact model.Artifact) os.FileMode {
+	if artifact.Mode == 0 {
+		return DefaultMode
+	}
+	return os.FileMode(artifact.Mode) & os.ModePerm
+}
+
+// dirWriter writes each artifact to a file within a directory
+type dirWriter struct {
+	dir   string
+	files []string
+}
+
+// NewDirWriter writes artifacts to files within a directory, which is created when needed. Each file is replaced
+// atomically, so an interrupted run never leaves a half-written file behind.
+func NewDirWriter(dir string) Writer {
+	return &dirWriter{dir: dir}
+}
+
+func (dw *dirWriter) Write(artifact model.Artifact) error {
+	cleaned, err := CleanPath(artifact.Path)
+	if err != nil {
+		return err
+	}
+	fileName := filepath.Join(dw.dir, filepath.FromSlash(cleaned))
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	tempFile, err := ioutil.TempFile(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tempFile.Write(artifact.Content)
+	if err == nil {
+		err = tempFile.Chmod(artifactMode(artifact))
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), fileName)
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("unable to write %s: %v", fileName, err)
+	}
+	dw.files = append(dw.files, fileName)
+	return nil
+}
+
+func (dw *dirWriter) Close() error {
+	return nil
+}
+
+func (dw *dirWriter) Files() []string {
+	return dw.files
+}
+
+// tarWriter writes every artifact into a single tarball
+type tarWriter struct {
+	name   string
+	closer io.Closer
+	tw     *tar.Writer
+	files  []string
+}
+
+// NewTarWriter writes artifacts into a tarball. name is used in the report; closer (which may be nil) is closed
+// once the tarball is complete.
+func NewTarWriter(name string, out io.Writer, closer io.Closer) Writer {
+	return &tarWriter{name: name, closer: closer, tw: tar.NewWriter(out)}
+}
+
+func (tw *tarWriter) Write(artifact model.Artifact) error {
+	cleaned, err := CleanPath(artifact.Path)
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: cleaned, Mode: int64(artifactMode(artifact)), Size: int64(len(artifact.Content)),
+		ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tw.tw.Write(artifact.Content); err != nil {
+		return err
+	}
+	if len(tw.files) == 0 && len(tw.name) > 0 {
+		tw.files = append(tw.files, tw.name)
+	}
+	return nil
+}
+
+func (tw *tarWriter) Close() error {
+	err := tw.tw.Close()
+	if tw.closer != nil {
+		if closeErr := tw.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (tw *tarWriter) Files() []string {
+	return tw.files
+}
+
+// streamWriter writes every artifact to a stream, as a series of YAML documents
+type streamWriter struct {
+	out io.Writer
+}
+
+// NewStreamWriter writes artifacts to a stream (e.g. stdout), separated by '---'. Each artifact is preceded by a
+// comment naming its path.
+func NewStreamWriter(out io.Writer) Writer {
+	return &streamWriter{out: out}
+}
+
+func (sw *streamWriter) Write(artifact model.Artifact) error {
+	cleaned, err := CleanPath(artifact.Path)
+	if err != nil {
+		return err
+	}
+	content := string(artifact.Content)
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	_, err = fmt.Fprintf(sw.out, "---\n# Source: %s\n%s", cleaned, content)
+	return err
+}
+
+func (sw *streamWriter) Close() error {
+	return nil
+}
+
+func (sw *streamWriter) Files() []string {
+	return nil
+}
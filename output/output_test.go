@@ -0,0 +1,107 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"rezolvr/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CleanPath(t *testing.T) {
+	for artifactPath, expected := range map[string]string{
+		"app.yaml":              "app.yaml",
+		"base/app.yaml":         "base/app.yaml",
+		"./base/../app.yaml":    "app.yaml",
+		"base//overlays/x.yaml": "base/overlays/x.yaml",
+		"":                      "",
+		"/etc/passwd":           "",
+		"../app.yaml":           "",
+		"base/../../app.yaml":   "",
+		"base/..":               "",
+	} {
+		cleaned, err := CleanPath(artifactPath)
+		if len(expected) == 0 {
+			assert.NotNil(t, err, artifactPath)
+		} else {
+			assert.Nil(t, err, artifactPath)
+			assert.Equal(t, expected, cleaned, artifactPath)
+		}
+	}
+}
+
+func Test_DirWriter(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "out")
+	writer := NewDirWriter(outputDir)
+	assert.Nil(t, writer.Write(model.Artifact{Path: "app.yaml", Content: []byte("first")}))
+	assert.Nil(t, writer.Write(model.Artifact{Path: "base/db.yaml", Content: []byte("db"), Mode: 0600}))
+	assert.Nil(t, writer.Write(model.Artifact{Path: "app.yaml", Content: []byte("second")}))
+	assert.NotNil(t, writer.Write(model.Artifact{Path: "../escape.yaml", Content: []byte("nope")}))
+	assert.Nil(t, writer.Close())
+
+	content, err := ioutil.ReadFile(filepath.Join(outputDir, "app.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "second", string(content))
+	info, err := os.Stat(filepath.Join(outputDir, "base", "db.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	_, err = os.Stat(filepath.Join(filepath.Dir(outputDir), "escape.yaml"))
+	assert.True(t, os.IsNotExist(err))
+
+	// No temporary files are left behind
+	entries, err := ioutil.ReadDir(outputDir)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, 3, len(writer.Files()))
+}
+
+func Test_TarAndStreamWriters(t *testing.T) {
+	artifacts := []model.Artifact{{Path: "app.yaml", Content: []byte("kind: Deployment")}, {Path: "base/db.yaml", Content: []byte("kind: Service\n")}}
+
+	buf := &bytes.Buffer{}
+	writer := NewTarWriter("out.tar", buf, nil)
+	for _, curArtifact := range artifacts {
+		assert.Nil(t, writer.Write(curArtifact))
+	}
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, []string{"out.tar"}, writer.Files())
+	reader := tar.NewReader(buf)
+	for _, curArtifact := range artifacts {
+		header, err := reader.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, curArtifact.Path, header.Name)
+		assert.Equal(t, int64(DefaultMode), header.Mode)
+		content, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, curArtifact.Content, content)
+	}
+	_, err := reader.Next()
+	assert.Equal(t, io.EOF, err)
+
+	buf = &bytes.Buffer{}
+	writer = NewStreamWriter(buf)
+	for _, curArtifact := range artifacts {
+		assert.Nil(t, writer.Write(curArtifact))
+	}
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, "---\n# Source: app.yaml\nkind: Deployment\n---\n# Source: base/db.yaml\nkind: Service\n", buf.String())
+}
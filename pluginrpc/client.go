@@ -0,0 +1,223 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"rezolvr/model"
+	"time"
+)
+
+// ExecutablePrefix - driver executables are named rezolvr-driver-<driver name>, e.g. rezolvr-driver-kube
+const ExecutablePrefix = "rezolvr-driver-"
+
+// shutdownTimeout is how long a driver has to exit once it's been asked to shut down
+const shutdownTimeout = 5 * time.Second
+
+// Client runs a driver executable, and implements model.RezolvrDriver by forwarding each call to it
+type Client struct {
+	name         string
+	version      string
+	capabilities model.Capabilities
+	conn         *conn
+	stdin        io.Closer
+	cmd          *exec.Cmd
+	nextID       int64
+	logger       model.Logger
+}
+
+// FindDriver locates the executable for a driver. The plugin directory is searched first
+// (<pluginDir>/<name>/rezolvr-driver-<name>, then <pluginDir>/rezolvr-driver-<name>), followed by the PATH.
+func FindDriver(pluginDir string, name string) (string, bool) {
+	executable := ExecutablePrefix + name
+	for _, curCandidate := range []string{filepath.Join(pluginDir, name, executable), filepath.Join(pluginDir, executable)} {
+		if info, err := os.Stat(curCandidate); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return curCandidate, true
+		}
+	}
+	if found, err := exec.LookPath(executable); err == nil {
+		return found, true
+	}
+	return "", false
+}
+
+// Start launches a driver executable, performs the protocol handshake, and asks the driver for its capabilities
+func Start(name string, executable string) (*Client, error) {
+	cmd := exec.Command(executable)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start driver %s: %v", executable, err)
+	}
+	client := newClient(name, stdout, stdin)
+	client.cmd = cmd
+	if err := client.handshake(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err := client.call(context.Background(), MethodCapabilities, struct{}{}, &client.capabilities); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("driver %s: unable to discover its capabilities: %v", name, err)
+	}
+	return client, nil
+}
+
+func newClient(name string, in io.Reader, out io.WriteCloser) *Client {
+	return &Client{name: name, conn: newConn(in, out), stdin: out, logger: model.GetLogger()}
+}
+
+func (c *Client) handshake() error {
+	result := HandshakeResult{}
+	if err := c.call(context.Background(), MethodHandshake, &HandshakeParams{ProtocolVersion: ProtocolVersion}, &result); err != nil {
+		return fmt.Errorf("driver %s: handshake failed: %v", c.name, err)
+	}
+	if result.ProtocolVersion != ProtocolVersion {
+		return fmt.Errorf("driver %s speaks protocol version %d, but rezolvr requires version %d", c.name, result.ProtocolVersion, ProtocolVersion)
+	}
+	c.version = result.Version
+	return nil
+}
+
+// call sends a request and waits for its response. Log notifications received in the meantime are passed to the logger.
+// When the context is cancelled, the driver is stopped.
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	if err := c.conn.send(&id, method, params); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.abort()
+		case <-done:
+		}
+	}()
+	for {
+		msg, err := c.conn.read()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if err == io.EOF {
+			return errors.New("the driver exited unexpectedly")
+		} else if err != nil {
+			return err
+		}
+		if msg.ID == nil && msg.Method == MethodLog {
+			logParams := LogParams{}
+			if err := json.Unmarshal(msg.Params, &logParams); err == nil {
+				logger := c.logger
+				for k, v := range logParams.Fields {
+					logger = logger.WithField(k, v)
+				}
+				logAt(logger, logParams.Level, logParams.Message)
+			}
+			continue
+		}
+		if msg.Error != nil {
+			if msg.Error.Code == ErrorDriver {
+				return errors.New(msg.Error.Message)
+			}
+			return msg.Error
+		}
+		if msg.ID == nil || *msg.ID != id {
+			return fmt.Errorf("unexpected message from the driver: %s", msg.Method)
+		}
+		if result != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, result)
+		}
+		return nil
+	}
+}
+
+// abort stops the driver without waiting for it, e.g. when a request is cancelled
+func (c *Client) abort() {
+	c.stdin.Close()
+	if c.cmd != nil && c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+}
+
+// Name - the name of the driver
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Version - the version the driver reported in the handshake
+func (c *Client) Version() string {
+	return c.version
+}
+
+// SetLogger - the driver's log messages are forwarded to this logger
+func (c *Client) SetLogger(logger model.Logger) {
+	c.logger = logger
+}
+
+// Capabilities - the capabilities the driver reported when it started
+func (c *Client) Capabilities() model.Capabilities {
+	return c.capabilities
+}
+
+// Validate asks the driver to check the request, before any output is generated
+func (c *Client) Validate(ctx context.Context, request *model.TransformRequest) error {
+	return c.call(ctx, MethodValidate, request, nil)
+}
+
+// Transform sends the components to the driver, which generates the output
+func (c *Client) Transform(ctx context.Context, request *model.TransformRequest) (*model.TransformResult, error) {
+	result := &model.TransformResult{}
+	if err := c.call(ctx, MethodTransform, request, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Close asks the driver to shut down, and waits for it to exit. A driver which doesn't exit in time is killed.
+func (c *Client) Close() error {
+	c.call(context.Background(), MethodShutdown, struct{}{}, nil)
+	c.stdin.Close()
+	if c.cmd == nil {
+		return nil
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- c.cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(shutdownTimeout):
+		c.cmd.Process.Kill()
+		return fmt.Errorf("driver %s did not shut down, and was stopped", c.name)
+	}
+}
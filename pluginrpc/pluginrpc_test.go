@@ -0,0 +1,166 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"rezolvr/model"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDriver struct {
+	logger      model.Logger
+	transformed map[string]*model.Component
+	pluginDir   string
+}
+
+func (td *testDriver) Name() string {
+	return "test"
+}
+
+func (td *testDriver) Version() string {
+	return "1.2.3"
+}
+
+func (td *testDriver) SetLogger(logger model.Logger) {
+	td.logger = logger
+}
+
+func (td *testDriver) Capabilities() model.Capabilities {
+	return model.Capabilities{APIVersion: model.DriverAPIVersion, ResourceTypes: []string{"service.web.app"}, OutputFormats: []string{"test"}}
+}
+
+func (td *testDriver) Validate(ctx context.Context, request *model.TransformRequest) error {
+	if len(request.PluginDir) == 0 {
+		return errors.New("no plugin directory")
+	}
+	return nil
+}
+
+func (td *testDriver) Transform(ctx context.Context, request *model.TransformRequest) (*model.TransformResult, error) {
+	td.transformed = request.UpdatedComponents
+	td.pluginDir = request.PluginDir
+	result := &model.TransformResult{}
+	for k, v := range request.UpdatedComponents {
+		td.logger.WithField(model.LogFieldComponent, k).Debugf("Transforming")
+		result.Artifacts = append(result.Artifacts, model.Artifact{Path: v.Name + ".test", Format: "test", Content: []byte(k), Mode: 0600})
+	}
+	return result, nil
+}
+
+type testLogger struct {
+	lines  *[]string
+	fields string
+}
+
+func (tl testLogger) add(level string, format string, args ...interface{}) {
+	*tl.lines = append(*tl.lines, level+" "+fmt.Sprintf(format, args...)+tl.fields)
+}
+func (tl testLogger) Errorf(format string, args ...interface{}) { tl.add("error", format, args...) }
+func (tl testLogger) Warnf(format string, args ...interface{})  { tl.add("warn", format, args...) }
+func (tl testLogger) Infof(format string, args ...interface{})  { tl.add("info", format, args...) }
+func (tl testLogger) Debugf(format string, args ...interface{}) { tl.add("debug", format, args...) }
+func (tl testLogger) Tracef(format string, args ...interface{}) { tl.add("trace", format, args...) }
+func (tl testLogger) WithField(key string, value string) model.Logger {
+	return testLogger{lines: tl.lines, fields: tl.fields + " " + key + "=" + value}
+}
+
+// startTestDriver connects a client to a driver served within the test process
+func startTestDriver(t *testing.T, driver model.RezolvrDriver) (*Client, chan error) {
+	toDriverReader, toDriverWriter := io.Pipe()
+	fromDriverReader, fromDriverWriter := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		err := Serve(driver, toDriverReader, fromDriverWriter)
+		fromDriverWriter.Close()
+		served <- err
+	}()
+	return newClient("test", fromDriverReader, toDriverWriter), served
+}
+
+func Test_ClientServer(t *testing.T) {
+	driver := &testDriver{}
+	client, served := startTestDriver(t, driver)
+	lines := make([]string, 0)
+	client.SetLogger(testLogger{lines: &lines})
+	assert.Nil(t, client.handshake())
+	assert.Equal(t, "test", client.Name())
+	assert.Equal(t, "1.2.3", client.Version())
+	assert.Nil(t, client.call(context.Background(), MethodCapabilities, struct{}{}, &client.capabilities))
+	assert.Equal(t, driver.Capabilities(), client.Capabilities())
+
+	components := map[string]*model.Component{
+		"resource.web.app:welcome": {Name: "welcome", Type: "resource.web.app", Provides: map[string]*model.Resource{
+			"service.web.app:welcome": {Name: "welcome", Type: "service.web.app", Params: map[string]*model.Param{"port": {Name: "port", Value: "8080"}}},
+		}},
+	}
+	request := &model.TransformRequest{UpdatedComponents: components, State: &model.State{}, PluginDir: "/plugins/test/"}
+	assert.Nil(t, client.Validate(context.Background(), request))
+	result, err := client.Transform(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Artifact{{Path: "welcome.test", Format: "test", Content: []byte("resource.web.app:welcome"), Mode: 0600}}, result.Artifacts)
+	assert.Equal(t, "/plugins/test/", driver.pluginDir)
+	assert.Equal(t, "8080", driver.transformed["resource.web.app:welcome"].Provides["service.web.app:welcome"].Params["port"].Value)
+	assert.Equal(t, []string{"debug Transforming component=resource.web.app:welcome"}, lines)
+
+	// Errors returned by the driver are passed back as they are
+	err = client.Validate(context.Background(), &model.TransformRequest{})
+	assert.Equal(t, "no plugin directory", err.Error())
+
+	assert.Nil(t, client.Close())
+	assert.Nil(t, <-served)
+}
+
+func Test_Cancel(t *testing.T) {
+	client, served := startTestDriver(t, &testDriver{})
+	assert.Nil(t, client.handshake())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.Transform(ctx, &model.TransformRequest{PluginDir: "/plugins/test/"})
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, client.Close())
+	assert.Nil(t, <-served)
+}
+
+func Test_ProtocolErrors(t *testing.T) {
+	client, _ := startTestDriver(t, &testDriver{})
+
+	err := client.call(context.Background(), MethodHandshake, &HandshakeParams{ProtocolVersion: ProtocolVersion + 1}, nil)
+	rpcErr, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, ErrorProtocolVersion, rpcErr.Code)
+
+	err = client.call(context.Background(), "bogus", struct{}{}, nil)
+	assert.Equal(t, ErrorMethodNotFound, err.(*Error).Code)
+	client.Close()
+
+	// Each message is a single line of JSON-RPC 2.0
+	out := &bytes.Buffer{}
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"handshake","params":{"protocolVersion":3}}` + "\n")
+	assert.Nil(t, Serve(&testDriver{}, in, out))
+	response := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &response))
+	assert.Equal(t, "2.0", response["jsonrpc"])
+	assert.Equal(t, float64(7), response["id"])
+	assert.Equal(t, map[string]interface{}{"protocolVersion": float64(3), "driver": "test", "version": "1.2.3"}, response["result"])
+}
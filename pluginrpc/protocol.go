@@ -0,0 +1,143 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pluginrpc runs drivers as separate executables. rezolvr and the driver exchange JSON-RPC 2.0 messages over the
+// driver's stdin and stdout, one message per line. The driver's stderr is passed through to rezolvr's stderr.
+package pluginrpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"rezolvr/model"
+	"sync"
+)
+
+// ProtocolVersion is incremented whenever the messages change in an incompatible way. rezolvr and the driver
+// exchange versions in the handshake, and refuse to continue when they differ.
+const ProtocolVersion = 3
+
+// Methods understood by a driver
+const (
+	MethodHandshake    = "handshake"
+	MethodCapabilities = "capabilities"
+	MethodValidate     = "validate"
+	MethodTransform    = "transform"
+	MethodShutdown     = "shutdown"
+)
+
+// MethodLog is a notification sent from the driver to rezolvr, so that the driver's messages honour rezolvr's log settings
+const MethodLog = "log"
+
+// Error codes. The negative codes below -32000 are defined by JSON-RPC 2.0.
+const (
+	ErrorParse           = -32700
+	ErrorInvalidRequest  = -32600
+	ErrorMethodNotFound  = -32601
+	ErrorInvalidParams   = -32602
+	ErrorInternal        = -32603
+	ErrorProtocolVersion = -32000
+	ErrorDriver          = -32001 // The driver's Validate or Transform returned an error
+)
+
+// Error is a JSON-RPC error
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// HandshakeParams is sent by rezolvr when the driver starts
+type HandshakeParams struct {
+	ProtocolVersion int `json:"protocolVersion"`
+}
+
+// HandshakeResult is the driver's reply to the handshake
+type HandshakeResult struct {
+	ProtocolVersion int    `json:"protocolVersion"`
+	Driver          string `json:"driver"`
+	Version         string `json:"version"`
+}
+
+// LogParams is a single log message from the driver
+type LogParams struct {
+	Level   string            `json:"level"`
+	Message string            `json:"msg"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// message holds any JSON-RPC message: a request, a notification (a request without an ID) or a response
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// conn reads and writes newline-delimited JSON-RPC messages. Writes may come from several goroutines (e.g. log messages).
+type conn struct {
+	decoder *json.Decoder
+	encoder *json.Encoder
+	mu      sync.Mutex
+}
+
+func newConn(in io.Reader, out io.Writer) *conn {
+	return &conn{decoder: json.NewDecoder(bufio.NewReader(in)), encoder: json.NewEncoder(out)}
+}
+
+func (c *conn) read() (*message, error) {
+	msg := &message{}
+	if err := c.decoder.Decode(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.encoder.Encode(msg)
+}
+
+// send writes a request (or a notification, when id is nil)
+func (c *conn) send(id *int64, method string, params interface{}) error {
+	content, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{ID: id, Method: method, Params: content})
+}
+
+// logAt writes a message to a logger at the named level
+func logAt(logger model.Logger, level string, msg string) {
+	switch level {
+	case "error":
+		logger.Errorf("%s", msg)
+	case "warn":
+		logger.Warnf("%s", msg)
+	case "debug":
+		logger.Debugf("%s", msg)
+	case "trace":
+		logger.Tracef("%s", msg)
+	default:
+		logger.Infof("%s", msg)
+	}
+}
@@ -0,0 +1,123 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"rezolvr/model"
+)
+
+// remoteLogger sends log messages to rezolvr as notifications
+type remoteLogger struct {
+	conn   *conn
+	fields map[string]string
+}
+
+func (rl *remoteLogger) log(level string, format string, args ...interface{}) {
+	// Log messages are best effort; a broken connection is reported by the next response
+	rl.conn.send(nil, MethodLog, &LogParams{Level: level, Message: fmt.Sprintf(format, args...), Fields: rl.fields})
+}
+
+func (rl *remoteLogger) Errorf(format string, args ...interface{}) { rl.log("error", format, args...) }
+func (rl *remoteLogger) Warnf(format string, args ...interface{})  { rl.log("warn", format, args...) }
+func (rl *remoteLogger) Infof(format string, args ...interface{})  { rl.log("info", format, args...) }
+func (rl *remoteLogger) Debugf(format string, args ...interface{}) { rl.log("debug", format, args...) }
+func (rl *remoteLogger) Tracef(format string, args ...interface{}) { rl.log("trace", format, args...) }
+
+func (rl *remoteLogger) WithField(key string, value string) model.Logger {
+	fields := map[string]string{key: value}
+	for k, v := range rl.fields {
+		if k != key {
+			fields[k] = v
+		}
+	}
+	return &remoteLogger{conn: rl.conn, fields: fields}
+}
+
+// Serve answers requests from rezolvr on behalf of a driver, until the input is closed or rezolvr asks the driver
+// to shut down. It's called from the driver executable's main function, with os.Stdin and os.Stdout.
+func Serve(driver model.RezolvrDriver, in io.Reader, out io.Writer) error {
+	c := newConn(in, out)
+	driver.SetLogger(&remoteLogger{conn: c})
+	for {
+		msg, err := c.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			c.write(&message{Error: &Error{Code: ErrorParse, Message: err.Error()}})
+			return err
+		}
+		if msg.ID == nil {
+			// Notifications don't need a reply, and none are currently expected
+			continue
+		}
+		result, rpcErr := handleRequest(driver, msg)
+		response := &message{ID: msg.ID, Error: rpcErr}
+		if rpcErr == nil {
+			if response.Result, err = json.Marshal(result); err != nil {
+				response.Error = &Error{Code: ErrorInternal, Message: err.Error()}
+			}
+		}
+		if err := c.write(response); err != nil {
+			return err
+		}
+		if msg.Method == MethodShutdown {
+			return nil
+		}
+	}
+}
+
+func handleRequest(driver model.RezolvrDriver, msg *message) (interface{}, *Error) {
+	switch msg.Method {
+	case MethodHandshake:
+		params := HandshakeParams{}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &Error{Code: ErrorInvalidParams, Message: err.Error()}
+		}
+		if params.ProtocolVersion != ProtocolVersion {
+			return nil, &Error{Code: ErrorProtocolVersion,
+				Message: fmt.Sprintf("unsupported protocol version %d; driver %s speaks version %d", params.ProtocolVersion, driver.Name(), ProtocolVersion)}
+		}
+		return &HandshakeResult{ProtocolVersion: ProtocolVersion, Driver: driver.Name(), Version: driver.Version()}, nil
+	case MethodCapabilities:
+		return driver.Capabilities(), nil
+	case MethodValidate, MethodTransform:
+		request := &model.TransformRequest{}
+		if err := json.Unmarshal(msg.Params, request); err != nil {
+			return nil, &Error{Code: ErrorInvalidParams, Message: err.Error()}
+		}
+		if request.State == nil {
+			request.State = &model.State{Components: map[string]*model.Component{}}
+		}
+		// Requests are handled one at a time; rezolvr stops the driver to cancel one
+		if msg.Method == MethodValidate {
+			if err := driver.Validate(context.Background(), request); err != nil {
+				return nil, &Error{Code: ErrorDriver, Message: err.Error()}
+			}
+			return struct{}{}, nil
+		}
+		result, err := driver.Transform(context.Background(), request)
+		if err != nil {
+			return nil, &Error{Code: ErrorDriver, Message: err.Error()}
+		}
+		return result, nil
+	case MethodShutdown:
+		return struct{}{}, nil
+	}
+	return nil, &Error{Code: ErrorMethodNotFound, Message: "unknown method: " + msg.Method}
+}
@@ -0,0 +1,210 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"rezolvr/model"
+	"sort"
+)
+
+// Error codes reported in the JSON output
+const (
+	CodeUsage    = "usage"
+	CodeConfig   = "config"
+	CodeLoad     = "load"
+	CodeState    = "invalid_state"
+	CodeResolve  = "resolve"
+	CodeDriver   = "driver"
+	CodeSave     = "save"
+	CodeExport   = "export"
+	CodeNotFound = "not_found"
+	CodeUnknown  = "error"
+)
+
+// CodedError attaches an error code to an error, so that tooling doesn't have to parse messages
+type CodedError struct {
+	Code string
+	Err  error
+}
+
+func (ce *CodedError) Error() string {
+	return ce.Err.Error()
+}
+
+// Unwrap allows errors.As to see the underlying error
+func (ce *CodedError) Unwrap() error {
+	return ce.Err
+}
+
+// WithCode wraps an error with a code. A nil error stays nil.
+func WithCode(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var existing *CodedError
+	if errors.As(err, &existing) {
+		return err
+	}
+	return &CodedError{Code: code, Err: err}
+}
+
+// Error is a single error within a report
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Change describes a param whose value changed (or would change) as a result of the command
+type Change struct {
+	Component string `json:"component"`
+	Section   string `json:"section"`
+	Resource  string `json:"resource"`
+	Param     string `json:"param"`
+	OldValue  string `json:"oldValue"`
+	NewValue  string `json:"newValue"`
+}
+
+// Resource is a simplified view of a resource: its type, name and param values
+type Resource struct {
+	Type   string            `json:"type"`
+	Name   string            `json:"name,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Component is a simplified view of a resolved component
+type Component struct {
+	ID       string               `json:"id"`
+	Name     string               `json:"name"`
+	Type     string               `json:"type"`
+	Provides map[string]*Resource `json:"provides,omitempty"`
+	Uses     map[string]*Resource `json:"uses,omitempty"`
+	Needs    map[string]*Resource `json:"needs,omitempty"`
+	// DeploymentHints - as declared by the component
+	DeploymentHints *model.DeploymentHints `json:"deploymentHints,omitempty"`
+}
+
+// Setting is a single configuration value, and where it came from
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Template is a driver template, and the source it's loaded from
+type Template struct {
+	Driver   string `json:"driver"`
+	Name     string `json:"name"`
+	Source   string `json:"source"`
+	Location string `json:"location"`
+}
+
+// Driver describes a driver, and what it supports
+type Driver struct {
+	Name          string   `json:"name"`
+	Version       string   `json:"version"`
+	Kind          string   `json:"kind"`
+	Location      string   `json:"location"`
+	APIVersion    int      `json:"apiVersion"`
+	ResourceTypes []string `json:"resourceTypes"`
+	OutputFormats []string `json:"outputFormats"`
+}
+
+// Report is the single JSON document written to stdout when '--output json' is used
+type Report struct {
+	Command            string       `json:"command"`
+	Version            string       `json:"version"`
+	Success            bool         `json:"success"`
+	Components         []*Component `json:"components,omitempty"`
+	Changes            []Change     `json:"changes,omitempty"`
+	EnvironmentChanges []string     `json:"environmentChanges,omitempty"`
+	Files              []string     `json:"files,omitempty"`
+	Config             []Setting    `json:"config,omitempty"`
+	Templates          []Template   `json:"templates,omitempty"`
+	Driver             *Driver      `json:"driver,omitempty"`
+	Errors             []Error      `json:"errors,omitempty"`
+}
+
+// New creates an empty report for a command
+func New(command string, version string) *Report {
+	return &Report{Command: command, Version: version}
+}
+
+// summarizeResources - the params of each resource. The values of secrets are masked: those of params which are
+// secrets, and those which hold the value of one (secretValues).
+func summarizeResources(resources map[string]*model.Resource, secretValues map[string]bool) map[string]*Resource {
+	if len(resources) == 0 {
+		return nil
+	}
+	summary := make(map[string]*Resource)
+	for resID, curResource := range resources {
+		res := &Resource{Type: curResource.Type, Name: curResource.Name, Params: make(map[string]string)}
+		for paramName, curParam := range curResource.Params {
+			if model.IsSecretParam(resID, paramName) || secretValues[curParam.Value] {
+				res.Params[paramName] = model.MaskValue(curParam.Value)
+			} else {
+				res.Params[paramName] = curParam.Value
+			}
+		}
+		summary[resID] = res
+	}
+	return summary
+}
+
+// AddComponents adds a summary of each component to the report, ordered by ID. The values of secrets are masked,
+// including the params which hold one of the secretValues.
+func (r *Report) AddComponents(components map[string]*model.Component, secretValues map[string]bool) {
+	ids := make([]string, 0, len(components))
+	for k := range components {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+	for _, curID := range ids {
+		curComponent := components[curID]
+		r.Components = append(r.Components, &Component{
+			ID:              curID,
+			Name:            curComponent.Name,
+			Type:            curComponent.Type,
+			Provides:        summarizeResources(curComponent.Provides, secretValues),
+			Uses:            summarizeResources(curComponent.Uses, secretValues),
+			Needs:           summarizeResources(curComponent.Needs, secretValues),
+			DeploymentHints: curComponent.DeploymentHints,
+		})
+	}
+}
+
+// AddError records an error, using its code when one is attached
+func (r *Report) AddError(err error) {
+	code := CodeUnknown
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		code = coded.Code
+	}
+	r.AddErrorWithCode(code, err)
+}
+
+// AddErrorWithCode records an error with an explicit code
+func (r *Report) AddErrorWithCode(code string, err error) {
+	r.Errors = append(r.Errors, Error{Code: code, Message: err.Error()})
+}
+
+// Write encodes the report as indented JSON
+func (r *Report) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -0,0 +1,80 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"rezolvr/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Report(t *testing.T) {
+	r := New("rezolvr apply", "0.0.1")
+	r.AddComponents(map[string]*model.Component{
+		"component.web.app:b": {Name: "b", Type: "web.app"},
+		"component.web.app:a": {Name: "a", Type: "web.app", Provides: map[string]*model.Resource{
+			"web.app:a": {Type: "web.app", Name: "a", Params: map[string]*model.Param{"port": {Value: "8080"}}},
+		}},
+	}, nil)
+	r.AddError(fmt.Errorf("wrapped: %w", WithCode(CodeResolve, errors.New("unable to resolve"))))
+	r.AddError(errors.New("plain"))
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, r.Write(buf))
+	decoded := Report{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "rezolvr apply", decoded.Command)
+	assert.False(t, decoded.Success)
+	assert.Equal(t, 2, len(decoded.Components))
+	assert.Equal(t, "component.web.app:a", decoded.Components[0].ID)
+	assert.Equal(t, "8080", decoded.Components[0].Provides["web.app:a"].Params["port"])
+	assert.Equal(t, []Error{{Code: CodeResolve, Message: "wrapped: unable to resolve"}, {Code: CodeUnknown, Message: "plain"}}, decoded.Errors)
+
+	// Codes aren't replaced once set, and nil stays nil
+	assert.Nil(t, WithCode(CodeLoad, nil))
+	var coded *CodedError
+	assert.True(t, errors.As(WithCode(CodeLoad, WithCode(CodeState, errors.New("x"))), &coded))
+	assert.Equal(t, CodeState, coded.Code)
+}
+
+func Test_ReportMasksSecrets(t *testing.T) {
+	r := New("rezolvr apply", "0.0.1")
+	r.AddComponents(map[string]*model.Component{
+		"environment.properties": {Name: "environment", Provides: map[string]*model.Resource{
+			"environment.secret:creds": {Type: "environment.secret", Name: "creds", Params: map[string]*model.Param{"user": {Value: "admin1"}}},
+			"environment.properties:dbEnvProps": {Type: "environment.properties", Name: "dbEnvProps", Params: map[string]*model.Param{
+				"db_password": {Value: "passwordie"}, "db_host": {Value: "mydb"}, "db_empty_password": {Value: ""}}},
+		}},
+		"resource.web.app:catalog": {Name: "catalog", Type: "resource.web.app", Uses: map[string]*model.Resource{
+			"environment": {Type: "environment", Params: map[string]*model.Param{"DB_CONN": {Value: "s3cret"}, "DB_USER": {Value: "admin1"}}},
+		}},
+	}, map[string]bool{"s3cret": true, "admin1": true})
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, r.Write(buf))
+	for _, curSecret := range []string{"admin1", "passwordie", "s3cret"} {
+		assert.NotContains(t, buf.String(), curSecret)
+	}
+	decoded := Report{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, map[string]string{"db_password": model.MaskedValue, "db_host": "mydb", "db_empty_password": ""},
+		decoded.Components[0].Provides["environment.properties:dbEnvProps"].Params)
+	assert.Equal(t, model.MaskedValue, decoded.Components[1].Uses["environment"].Params["DB_CONN"])
+}
@@ -0,0 +1,162 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package templates locates the templates used by the drivers. Each template is looked for in an ordered search path:
+// the project's .rezolvr/templates/<driver>/ directory, the user's <pluginDir>/<driver>/templates/ directory, and finally
+// the templates embedded in the driver. The first match wins, so a single template can be customized without copying the rest.
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Extension is the file extension of every template
+const Extension = ".template"
+
+// ProjectDir holds the project's template overrides, one subdirectory per driver
+var ProjectDir = filepath.Join(".rezolvr", "templates")
+
+// Names of the sources within the search path
+const (
+	SourceProject  = "project"
+	SourceUser     = "user"
+	SourceEmbedded = "embedded"
+)
+
+// ErrNotFound is returned when a template isn't found anywhere in the search path
+var ErrNotFound = errors.New("template not found")
+
+// Source is a single location within the search path
+type Source struct {
+	Name     string
+	Location string
+	FS       fs.FS
+}
+
+// Template is a template, along with the source it was loaded from
+type Template struct {
+	Name     string
+	Source   string
+	Location string
+	Content  []byte
+}
+
+// Loader loads a driver's templates from its search path
+type Loader struct {
+	DriverName string
+	Sources    []Source
+}
+
+// ProjectTemplateDir - the directory containing the project's template overrides for a driver
+func ProjectTemplateDir(driverName string) string {
+	return filepath.Join(ProjectDir, driverName)
+}
+
+// NewLoader creates a loader for a driver. driverDir is the driver's directory within the plugin directory (as passed to
+// TransformComponents), and embedded holds the driver's built-in templates; it may be nil for drivers without any.
+func NewLoader(driverName string, driverDir string, embedded fs.FS) *Loader {
+	loader := &Loader{DriverName: driverName}
+	for _, curSource := range []struct{ name, dir string }{
+		{SourceProject, ProjectTemplateDir(driverName)},
+		{SourceUser, filepath.Join(driverDir, "templates")},
+	} {
+		loader.Sources = append(loader.Sources, Source{Name: curSource.name, Location: curSource.dir, FS: os.DirFS(curSource.dir)})
+	}
+	if embedded != nil {
+		loader.Sources = append(loader.Sources, Source{Name: SourceEmbedded, Location: "(compiled into rezolvr)", FS: embedded})
+	}
+	return loader
+}
+
+// Load finds a template by name (e.g. "service.web.app"), using the first source which contains it
+func (l *Loader) Load(name string) (*Template, error) {
+	return l.load(name, l.Sources)
+}
+
+func (l *Loader) load(name string, sources []Source) (*Template, error) {
+	fileName := name + Extension
+	for _, curSource := range sources {
+		content, err := fs.ReadFile(curSource.FS, fileName)
+		if err == nil {
+			return &Template{Name: name, Source: curSource.Name, Location: sourceLocation(curSource, fileName), Content: content}, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("error loading template %s from %s: %v", name, curSource.Location, err)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s (driver %s)", ErrNotFound, name, l.DriverName)
+}
+
+// List returns the templates the loader would use, ordered by name. Templates which are overridden aren't included.
+func (l *Loader) List() ([]*Template, error) {
+	found := make(map[string]*Template)
+	for _, curSource := range l.Sources {
+		fileNames, err := fs.Glob(curSource.FS, "*"+Extension)
+		if err != nil {
+			return nil, err
+		}
+		for _, curFileName := range fileNames {
+			name := strings.TrimSuffix(curFileName, Extension)
+			if _, exists := found[name]; !exists {
+				found[name] = &Template{Name: name, Source: curSource.Name, Location: sourceLocation(curSource, curFileName)}
+			}
+		}
+	}
+	results := make([]*Template, 0, len(found))
+	for _, curTemplate := range found {
+		results = append(results, curTemplate)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
+
+// Eject copies a template into the project's override directory, so that it can be customized. The copy comes from the
+// first source after the project itself; an existing override is only replaced when overwrite is set. The name of the
+// new file is returned.
+func (l *Loader) Eject(name string, overwrite bool) (string, error) {
+	baseSources := make([]Source, 0, len(l.Sources))
+	for _, curSource := range l.Sources {
+		if curSource.Name != SourceProject {
+			baseSources = append(baseSources, curSource)
+		}
+	}
+	curTemplate, err := l.load(name, baseSources)
+	if err != nil {
+		return "", err
+	}
+	targetDir := ProjectTemplateDir(l.DriverName)
+	targetFile := filepath.Join(targetDir, name+Extension)
+	if _, err := os.Stat(targetFile); err == nil && !overwrite {
+		return "", fmt.Errorf("the template has already been ejected: %s (use --force to replace it)", targetFile)
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return "", err
+	}
+	return targetFile, os.WriteFile(targetFile, curTemplate.Content, 0644)
+}
+
+func sourceLocation(source Source, fileName string) string {
+	if source.Name == SourceEmbedded {
+		return source.Location
+	}
+	return filepath.Join(source.Location, fileName)
+}
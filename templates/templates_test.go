@@ -0,0 +1,78 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Loader(t *testing.T) {
+	baseDir := t.TempDir()
+	ProjectDir = filepath.Join(baseDir, "project")
+	driverDir := filepath.Join(baseDir, "plugins", "test")
+	embedded := fstest.MapFS{
+		"service.web.app.template": {Data: []byte("embedded app")},
+		"storage.volume.template":  {Data: []byte("embedded volume")},
+		"service.db.template":      {Data: []byte("embedded db")},
+	}
+	assert.Nil(t, os.MkdirAll(filepath.Join(driverDir, "templates"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(driverDir, "templates", "storage.volume.template"), []byte("user volume"), 0644))
+	assert.Nil(t, os.MkdirAll(ProjectTemplateDir("test"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(ProjectTemplateDir("test"), "service.db.template"), []byte("project db"), 0644))
+
+	loader := NewLoader("test", driverDir, embedded)
+	for name, expected := range map[string]struct{ source, content string }{
+		"service.web.app": {SourceEmbedded, "embedded app"},
+		"storage.volume":  {SourceUser, "user volume"},
+		"service.db":      {SourceProject, "project db"},
+	} {
+		loaded, err := loader.Load(name)
+		assert.Nil(t, err)
+		assert.Equal(t, expected.source, loaded.Source, name)
+		assert.Equal(t, expected.content, string(loaded.Content), name)
+	}
+	_, err := loader.Load("missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	listed, err := loader.List()
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for _, curTemplate := range listed {
+		names = append(names, curTemplate.Name+":"+curTemplate.Source)
+	}
+	assert.Equal(t, []string{"service.db:project", "service.web.app:embedded", "storage.volume:user"}, names)
+
+	// Ejecting copies the next template in the search path, and doesn't replace an existing override unless asked to
+	fileName, err := loader.Eject("service.web.app", false)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(ProjectTemplateDir("test"), "service.web.app.template"), fileName)
+	loaded, err := loader.Load("service.web.app")
+	assert.Nil(t, err)
+	assert.Equal(t, SourceProject, loaded.Source)
+	assert.Equal(t, "embedded app", string(loaded.Content))
+	_, err = loader.Eject("service.db", false)
+	assert.NotNil(t, err)
+	_, err = loader.Eject("service.db", true)
+	assert.Nil(t, err)
+	loaded, err = loader.Load("service.db")
+	assert.Nil(t, err)
+	assert.Equal(t, "embedded db", string(loaded.Content))
+}
@@ -0,0 +1,130 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"rezolvr/drivers"
+	"rezolvr/model"
+	"rezolvr/pluginrpc"
+	"sort"
+	"strings"
+)
+
+// DriverKindBuiltIn - the driver is compiled into rezolvr, and registered with the drivers package
+const DriverKindBuiltIn = "built-in"
+
+// DriverKindExecutable - the driver runs as a separate executable, speaking the pluginrpc protocol
+const DriverKindExecutable = "executable"
+
+// DriverKindLegacyPlugin - the driver is a Go plugin (.so), which must be built with the same toolchain as rezolvr
+const DriverKindLegacyPlugin = "legacy plugin"
+
+// DriverInfo describes an available driver, and where it was found
+type DriverInfo struct {
+	Name     string
+	Kind     string
+	Location string
+}
+
+// builtInLocation - reported as the location of the built-in drivers
+const builtInLocation = "(compiled into rezolvr)"
+
+// legacyPluginPath - the location of a driver's Go plugin within the plugin directory
+func legacyPluginPath(pluginDir string, driverName string) string {
+	return filepath.Join(pluginDir, driverName, "plugin"+driverName+".so")
+}
+
+// LoadDriver - locate and start a driver. Built-in drivers are preferred, then driver executables; Go plugins (.so) are
+// used when neither is found. The returned driver may also be an io.Closer, which should be closed once it's no longer needed.
+func LoadDriver(pluginDir string, driverName string) (model.RezolvrDriver, *DriverInfo, error) {
+	if driver, ok := drivers.Lookup(driverName); ok {
+		return driver, &DriverInfo{Name: driverName, Kind: DriverKindBuiltIn, Location: builtInLocation}, nil
+	}
+
+	if executable, ok := pluginrpc.FindDriver(pluginDir, driverName); ok {
+		model.GetLogger().Debugf("Starting driver executable: %s", executable)
+		client, err := pluginrpc.Start(driverName, executable)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, &DriverInfo{Name: driverName, Kind: DriverKindExecutable, Location: executable}, nil
+	}
+
+	pluginFile := legacyPluginPath(pluginDir, driverName)
+	if _, err := os.Stat(pluginFile); err == nil {
+		model.GetLogger().Debugf("Attempting to load plugin: %s", pluginFile)
+		driver, err := LoadPlugin(pluginFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return driver, &DriverInfo{Name: driverName, Kind: DriverKindLegacyPlugin, Location: pluginFile}, nil
+	}
+	return nil, nil, fmt.Errorf("driver not found: %s (built-in drivers: %s; also looked for %s%s in %s and on the PATH, and for %s)",
+		driverName, strings.Join(drivers.Names(), ", "), pluginrpc.ExecutablePrefix, driverName, pluginDir, pluginFile)
+}
+
+// FindDrivers - list the built-in drivers, and the drivers within the plugin directory and on the PATH, ordered by name.
+// When a driver is found in several places, the one LoadDriver would use is listed.
+func FindDrivers(pluginDir string) []*DriverInfo {
+	names := make(map[string]bool)
+	for _, curName := range drivers.Names() {
+		names[curName] = true
+	}
+	addName := func(fileName string) {
+		if strings.HasPrefix(fileName, pluginrpc.ExecutablePrefix) {
+			names[strings.TrimPrefix(fileName, pluginrpc.ExecutablePrefix)] = true
+		}
+	}
+	if entries, err := ioutil.ReadDir(pluginDir); err == nil {
+		for _, curEntry := range entries {
+			if curEntry.IsDir() {
+				names[curEntry.Name()] = true
+			} else {
+				addName(curEntry.Name())
+			}
+		}
+	}
+	for _, curDir := range filepath.SplitList(os.Getenv("PATH")) {
+		if entries, err := ioutil.ReadDir(curDir); err == nil {
+			for _, curEntry := range entries {
+				addName(curEntry.Name())
+			}
+		}
+	}
+
+	found := make([]*DriverInfo, 0)
+	for curName := range names {
+		if _, ok := drivers.Lookup(curName); ok {
+			found = append(found, &DriverInfo{Name: curName, Kind: DriverKindBuiltIn, Location: builtInLocation})
+		} else if executable, ok := pluginrpc.FindDriver(pluginDir, curName); ok {
+			found = append(found, &DriverInfo{Name: curName, Kind: DriverKindExecutable, Location: executable})
+		} else if pluginFile := legacyPluginPath(pluginDir, curName); fileExists(pluginFile) {
+			found = append(found, &DriverInfo{Name: curName, Kind: DriverKindLegacyPlugin, Location: pluginFile})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Name < found[j].Name
+	})
+	return found
+}
+
+func fileExists(fileName string) bool {
+	info, err := os.Stat(fileName)
+	return err == nil && !info.IsDir()
+}
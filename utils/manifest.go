@@ -0,0 +1,98 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"rezolvr/config"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ManifestFileName is the name of the project manifest which is picked up from the current directory
+const ManifestFileName = "rezolvr.yaml"
+
+// Manifest describes a rezolvr project, so that commands can be run without any flags.
+// Relative paths are relative to the directory containing the manifest.
+// The manifest may also hold any of the configuration settings (pluginDir, outputDir, logLevel, etc).
+type Manifest struct {
+	Components      []string `yaml:"components"`
+	Environments    []string `yaml:"environments"`
+	State           string   `yaml:"state"`
+	Driver          string   `yaml:"driver,omitempty"`
+	config.Settings `yaml:",inline"`
+
+	baseDir string
+}
+
+// LoadManifest - load a project manifest. If the file doesn't exist and mustExist is false, nil is returned
+func LoadManifest(fileName string, mustExist bool) (*Manifest, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		if !mustExist && os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	err = yaml.UnmarshalStrict(content, manifest)
+	if err != nil {
+		return nil, err
+	}
+	manifest.baseDir = filepath.Dir(fileName)
+	return manifest, nil
+}
+
+// resolvePath - make a manifest path relative to the manifest's directory
+func (m *Manifest) resolvePath(path string) string {
+	if len(path) == 0 || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(m.baseDir, path)
+}
+
+func (m *Manifest) resolvePaths(paths []string) []string {
+	resolved := make([]string, len(paths))
+	for idx, curPath := range paths {
+		resolved[idx] = m.resolvePath(curPath)
+	}
+	return resolved
+}
+
+// ApplyTo - fill in any command line arguments which weren't specified. Command line arguments always take precedence.
+func (m *Manifest) ApplyTo(cla *CmdLineArgs) {
+	if len(cla.ComponentsToAdd) == 0 && cla.Command != "refresh" {
+		cla.ComponentsToAdd = m.resolvePaths(m.Components)
+	}
+	if len(cla.EnvFiles) == 0 {
+		cla.EnvFiles = m.resolvePaths(m.Environments)
+	}
+	if len(cla.StateFile) == 0 {
+		cla.StateFile = m.resolvePath(m.State)
+	}
+	if len(cla.Driver) == 0 {
+		cla.Driver = m.Driver
+	}
+}
+
+// GetSettings - the configuration settings held in the manifest, with paths relative to the manifest's directory
+func (m *Manifest) GetSettings() *config.Settings {
+	settings := m.Settings
+	settings.ResolvePaths(m.baseDir)
+	return &settings
+}
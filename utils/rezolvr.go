@@ -17,8 +17,6 @@ package utils
 import (
 	"bytes"
 	"errors"
-	"fmt"
-	"log"
 	"rezolvr/model"
 	"text/template"
 )
@@ -125,15 +123,17 @@ func ResolveAllComponents(state *model.State, componentsToResolve map[string]*mo
 		// TODO: Catch circular & unresolved dependencies
 		unresolvedComponentCount = len(componentsToResolve)
 		if unresolvedComponentCount > 0 && attempts > model.RetryCount {
+			for curComponentID := range componentsToResolve {
+				model.GetLogger().WithField(model.LogFieldComponent, curComponentID).Errorf("Unable to resolve component")
+			}
 			err := errors.New("Dependency infinite loop encountered. This is usually due to a missing resource. Please fix")
-			log.Println("Dependency infinite loop encountered... please fix")
 			return nil, err
 		}
 	}
 	return fullyResolvedComponents, nil
 }
 
-func resolveNeedParams(needParams map[string]*model.Param, providedParams map[string]*model.Param, res *model.Component) int {
+func resolveNeedParams(logger model.Logger, needParams map[string]*model.Param, providedParams map[string]*model.Param, res *model.Component) int {
 	rezolvrStatus := RESOLVED
 	for _, curNeedParam := range needParams {
 		if curNeedParam.RezolvrStatus == UNRESOLVED {
@@ -153,8 +153,7 @@ func resolveNeedParams(needParams map[string]*model.Param, providedParams map[st
 					curNeedParam.RezolvrStatus = RESOLVED
 				} else if curNeedParam.Required {
 					// No value has been found for a required parameter. Throw an error
-					msg := fmt.Sprintf("Missing required parameter for %s - %s: %s", res.Name, res.Type, curNeedParam.Name)
-					log.Println(msg)
+					logger.WithField(model.LogFieldParam, curNeedParam.Name).Warnf("Missing required parameter for %s - %s: %s", res.Name, res.Type, curNeedParam.Name)
 					rezolvrStatus = UNRESOLVED
 				}
 			}
@@ -199,7 +198,8 @@ func locateProviderFromModifiedComponents(pType string, providedName string, com
 func resolveComponentNeeds(state *model.State, comp *model.Component, componentsToResolve map[string]*model.Component) int {
 	// Given the existing state of the system, and a target environment,
 	// attempt to resolve a component's needs
-	log.Printf("The type of component to be resolved: %s\n", comp.Type)
+	logger := model.GetLogger().WithField(model.LogFieldComponent, comp.ID())
+	logger.Debugf("The type of component to be resolved: %s", comp.Type)
 	env := state.Components["environment.properties"]
 	needsRezolvrStatus := RESOLVED
 
@@ -234,26 +234,26 @@ func resolveComponentNeeds(state *model.State, comp *model.Component, components
 		}
 
 		if !stateProviderOk && !envProviderOk && !modResourceProviderOk {
-			msg := fmt.Sprintf("Need missing for %s - %s: %s %s", comp.Name, comp.Type, curNeed.Type, curNeed.Name)
-			log.Println(msg)
+			logger.WithField(model.LogFieldResource, curNeed.Type+model.IDSeparator+curNeed.Name).Debugf("Need missing for %s - %s: %s %s", comp.Name, comp.Type, curNeed.Type, curNeed.Name)
 			return UNRESOLVED
 		}
 
-		paramsRezolvrStatus := resolveNeedParams(curNeed.Params, combinedProvidedParams, comp)
+		paramsRezolvrStatus := resolveNeedParams(logger.WithField(model.LogFieldResource, curNeed.Type+model.IDSeparator+curNeed.Name), curNeed.Params, combinedProvidedParams, comp)
 		curNeed.RezolvrStatus = paramsRezolvrStatus
 		if paramsRezolvrStatus == UNRESOLVED {
 			needsRezolvrStatus = UNRESOLVED
 		}
 	}
 	comp.NeedsRezolvrStatus = needsRezolvrStatus
-	log.Printf("Resulting status for current needs: %v", comp.NeedsRezolvrStatus)
+	logger.Tracef("Resulting status for current needs: %v", comp.NeedsRezolvrStatus)
 	return comp.NeedsRezolvrStatus
 }
 
 func resolveComponentUses(state *model.State, component *model.Component) int {
 
 	// Determine if any formulas exist in the 'uses' section
-	log.Printf("Resolving any 'uses' formulas for: %s \n", component.Type)
+	logger := model.GetLogger().WithField(model.LogFieldComponent, component.ID())
+	logger.Tracef("Resolving any 'uses' formulas for: %s", component.Type)
 
 	// The following two variables are made available to the eval() method
 	data := map[string]interface{}{
@@ -266,13 +266,13 @@ func resolveComponentUses(state *model.State, component *model.Component) int {
 		for _, curUseParam := range curUse.Params {
 
 			if len(curUseParam.Formula) > 0 {
-				log.Printf("Current formula to resolve: %v\n", curUseParam.Formula)
+				paramLogger := logger.WithField(model.LogFieldParam, curUseParam.Name)
+				paramLogger.Tracef("Current formula to resolve: %v", curUseParam.Formula)
 				t := template.Must(template.New("").Parse(curUseParam.Formula))
 				buf := &bytes.Buffer{}
 				err := t.Execute(buf, data)
 				if err != nil {
-					msg := fmt.Sprintf("Error resolving a 'Uses' formula: %s", curUseParam.Formula)
-					log.Printf(msg)
+					paramLogger.Errorf("Error resolving a 'uses' formula: %s: %v", curUseParam.Formula, err)
 				} else {
 					stringVal := buf.String()
 					curUseParam.Value = stringVal
@@ -283,14 +283,15 @@ func resolveComponentUses(state *model.State, component *model.Component) int {
 	markUsesElementsResolvedStatus(component.Uses, RESOLVED)
 	component.UsesRezolvrStatus = RESOLVED
 
-	log.Println("All uses forumlas successfully executed")
+	logger.Tracef("All uses formulas successfully executed")
 	return component.UsesRezolvrStatus
 }
 
 func resolveComponentProvides(state *model.State, component *model.Component) int {
 	// This should only be called after all of the resource needs have been resolved
 	// Determine if any formulas exist in the 'provides' section
-	log.Printf("Resolving any 'provides' formulas for: %s \n", component.Type)
+	logger := model.GetLogger().WithField(model.LogFieldComponent, component.ID())
+	logger.Tracef("Resolving any 'provides' formulas for: %s", component.Type)
 
 	// The following two variables are made available to the eval() method
 	data := map[string]interface{}{
@@ -303,13 +304,13 @@ func resolveComponentProvides(state *model.State, component *model.Component) in
 		for _, curProvideParam := range curProvide.Params {
 
 			if len(curProvideParam.Formula) > 0 {
-				log.Printf("Current formula to resolve: %v\n", curProvideParam.Formula)
+				paramLogger := logger.WithField(model.LogFieldParam, curProvideParam.Name)
+				paramLogger.Tracef("Current formula to resolve: %v", curProvideParam.Formula)
 				t := template.Must(template.New("").Parse(curProvideParam.Formula))
 				buf := &bytes.Buffer{}
 				err := t.Execute(buf, data)
 				if err != nil {
-					msg := fmt.Sprintf("Error resolving a 'provides' formula: %s", curProvideParam.Formula)
-					log.Printf(msg)
+					paramLogger.Errorf("Error resolving a 'provides' formula: %s: %v", curProvideParam.Formula, err)
 				} else {
 					stringVal := buf.String()
 					curProvideParam.Value = stringVal
@@ -320,6 +321,6 @@ func resolveComponentProvides(state *model.State, component *model.Component) in
 	markProvidesElementsResolvedStatus(component.Provides, RESOLVED)
 	component.ProvidesRezolvrStatus = RESOLVED
 
-	log.Println("All provides forumlas successfully executed")
+	logger.Tracef("All provides formulas successfully executed")
 	return component.ProvidesRezolvrStatus
 }
@@ -15,105 +15,194 @@
 package utils
 
 import (
-	"errors"
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
 	"plugin"
 	"rezolvr/model"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
 )
 
-// CmdLineArgs is a simplified structure for managing the command line arguments
+// CmdLineArgs is a simplified structure for managing the command line arguments, once they have been parsed
 type CmdLineArgs struct {
 	Command            string
 	Subcommand         string
-	EnvFile            string
+	EnvFiles           []string
 	StateFile          string
 	ExportFile         string
 	OutputDir          string
+	OutputTar          string
+	OutputStdout       bool
 	ComponentsToAdd    []string
 	ComponentsToDelete []string
+	SyncEnvironment    bool
+	ProjectFile        string
+	Driver             string
+	Strict             bool
+}
+
+// isYamlFile - determine if a file name has a YAML extension
+func isYamlFile(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	return ext == ".yaml" || ext == ".yml"
 }
 
-// ParseArgs - parse command line arguments
-func ParseArgs(args []string) (*CmdLineArgs, error) {
-	idx := 1
-	if len(args) < 3 {
-		return nil, errors.New("command line arguments missing")
+// findYamlFiles - recursively collect all of the YAML files within a directory, skipping any excluded directory
+func findYamlFiles(dir string, excluded map[string]bool) ([]string, error) {
+	found := make([]string, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != dir && excluded[filepath.Clean(path)] {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && isYamlFile(path) {
+			found = append(found, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	cla := CmdLineArgs{}
-	cla.ComponentsToAdd = make([]string, 0)
-	cla.Command = args[idx]
-	// The default output location is './out/'
-	cla.OutputDir = "./out/"
-
-	idx++
-	// The keyword 'whatif' is reserved for future use
-	if cla.Command == "whatif" {
-		cla.Subcommand = args[idx]
-		idx++
+	sort.Strings(found)
+	return found, nil
+}
+
+// componentKeys - the fields of a component document
+var componentKeys = map[string]bool{"name": true, "type": true, "description": true, "provides": true, "uses": true,
+	"needs": true, "deploymentHints": true}
+
+// isComponentFile - whether every document within a file is a component. Environment files (which select a driver),
+// state files, project manifests, and generated files (e.g. Kubernetes objects or a compose file) have other fields.
+func isComponentFile(fileName string) (bool, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return false, err
 	}
-	for idx < len(args) {
-		flag := args[idx]
-		idx++
-		// Make sure each flag has a target value
-		if idx >= len(args) {
-			return nil, errors.New("Unmatching command line args")
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		doc := make(map[string]interface{})
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			// Reported when the file is loaded
+			return true, nil
 		}
-		target := args[idx]
-		idx++
-		if flag == "-a" || flag == "--add-component" {
-			cla.ComponentsToAdd = append(cla.ComponentsToAdd, target)
-		} else if flag == "-d" || flag == "--delete-component" {
-			cla.ComponentsToDelete = append(cla.ComponentsToDelete, target)
-		} else if flag == "-e" || flag == "--environment" {
-			cla.EnvFile = target
-		} else if flag == "-s" || flag == "--source" {
-			cla.StateFile = target
-		} else if flag == "-x" || flag == "--export" {
-			cla.ExportFile = target
-		} else if flag == "-o" || flag == "--output-dir" {
-			cla.OutputDir = target
-		} else {
-			log.Printf("Warning: Command line argument unknown: %s. Ignoring.", flag)
+		for k := range doc {
+			if !componentKeys[k] {
+				model.GetLogger().Debugf("Skipping %s, which isn't a component file (it has a '%s' field)", fileName, k)
+				return false, nil
+			}
 		}
 	}
+}
+
+// ExpandComponentPaths - convert a list of files, directories and glob patterns into a list of component files.
+// Directories are searched recursively for YAML files. Any file or directory in the exclusion list (e.g. the state
+// file, the environment files, or the output directory, which often live alongside the components) is skipped, and so
+// is any file found within a directory or by a pattern which isn't a component file (e.g. another environment file).
+func ExpandComponentPaths(paths []string, exclude []string) ([]string, error) {
+	excluded := make(map[string]bool)
+	for _, curExclude := range exclude {
+		if len(curExclude) > 0 {
+			excluded[filepath.Clean(curExclude)] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	expanded := make([]string, 0)
+	addFile := func(fileName string, explicit bool) error {
+		cleanName := filepath.Clean(fileName)
+		if seen[cleanName] || (!explicit && excluded[cleanName]) {
+			return nil
+		}
+		if !explicit {
+			isComponent, err := isComponentFile(fileName)
+			if err != nil || !isComponent {
+				return err
+			}
+		}
+		seen[cleanName] = true
+		expanded = append(expanded, fileName)
+		return nil
+	}
 
-	return &cla, nil
+	for _, curPath := range paths {
+		matches := []string{curPath}
+		isPattern := strings.ContainsAny(curPath, "*?[")
+		if isPattern {
+			var err error
+			matches, err = filepath.Glob(curPath)
+			if err != nil {
+				return nil, fmt.Errorf("invalid component pattern %s: %v", curPath, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no component files match: %s", curPath)
+			}
+		}
+		for _, curMatch := range matches {
+			info, err := os.Stat(curMatch)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				dirFiles, err := findYamlFiles(curMatch, excluded)
+				if err != nil {
+					return nil, err
+				}
+				for _, curFile := range dirFiles {
+					if err := addFile(curFile, false); err != nil {
+						return nil, err
+					}
+				}
+			} else if !isPattern || isYamlFile(curMatch) {
+				// Explicitly named files are always loaded
+				if err := addFile(curMatch, !isPattern); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return expanded, nil
 }
 
 // LoadPlugin - Attempt to dynamically load a plugin
 func LoadPlugin(pluginPathAndName string) (model.RezolvrDriver, error) {
 	curPlugin, err := plugin.Open(pluginPathAndName)
 	if err != nil {
-		msg := fmt.Sprintf("Error loading plugin: %v", err)
-		log.Println(msg)
+		model.GetLogger().Debugf("Error loading plugin: %v", err)
 		return nil, err
 	}
 
 	foundPlugin, err := curPlugin.Lookup("RezolvrDriver")
 	if err != nil {
-		msg := fmt.Sprintf("Error loading the Rezolvr plugin: %v . Error: %v", pluginPathAndName, err)
-		log.Println(msg)
+		model.GetLogger().Debugf("Error loading the Rezolvr plugin: %v . Error: %v", pluginPathAndName, err)
 		return nil, err
 	}
 
 	rDriver, ok := foundPlugin.(model.RezolvrDriver)
 	if !ok {
-		log.Println("Plugin methods not found")
-		return nil, err
+		model.GetLogger().Debugf("Plugin methods not found")
+		return nil, fmt.Errorf("plugin does not implement the driver interface: %s", pluginPathAndName)
 	}
 	return rDriver, nil
 }
 
-// LoadFile - Load a file from disk
+// LoadFile - Load a file from disk. Unless the file must exist, a missing file has no content.
 func LoadFile(filename string, mustExist bool) ([]byte, error) {
 	content, err := ioutil.ReadFile(filename)
 	if err != nil {
 		if mustExist == false && os.IsNotExist(err) {
-			emptyContent := make([]byte, 1)
-			return emptyContent, nil
+			return []byte{}, nil
 		}
 		return nil, err
 	}
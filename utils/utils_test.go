@@ -0,0 +1,160 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"rezolvr/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFiles(t *testing.T, baseDir string, fileNames ...string) {
+	for _, curName := range fileNames {
+		fullName := filepath.Join(baseDir, curName)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(fullName), 0755))
+		assert.Nil(t, ioutil.WriteFile(fullName, []byte("name: test\n"), 0644))
+	}
+}
+
+func Test_ExpandComponentPaths(t *testing.T) {
+	baseDir := t.TempDir()
+	writeTestFiles(t, baseDir, "rezolvr/db.yaml", "rezolvr/app.yml", "rezolvr/nested/cache.yaml",
+		"rezolvr/notes.txt", "rezolvr/state.yaml", "rezolvr/env.yaml", "other/registry.yaml")
+	stateFile := filepath.Join(baseDir, "rezolvr/state.yaml")
+	envFile := filepath.Join(baseDir, "rezolvr/env.yaml")
+
+	// Directories are searched recursively, skipping non-YAML and excluded files
+	files, err := ExpandComponentPaths([]string{filepath.Join(baseDir, "rezolvr")}, []string{stateFile, envFile})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(baseDir, "rezolvr/app.yml"),
+		filepath.Join(baseDir, "rezolvr/db.yaml"),
+		filepath.Join(baseDir, "rezolvr/nested/cache.yaml"),
+	}, files)
+
+	// Glob patterns and plain files can be mixed; duplicates are dropped
+	files, err = ExpandComponentPaths([]string{filepath.Join(baseDir, "*/registry.yaml"), filepath.Join(baseDir, "rezolvr/db.yaml"),
+		filepath.Join(baseDir, "rezolvr/d*.yaml")}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(baseDir, "other/registry.yaml"), filepath.Join(baseDir, "rezolvr/db.yaml")}, files)
+
+	// A pattern which matches nothing is an error
+	_, err = ExpandComponentPaths([]string{filepath.Join(baseDir, "missing/*.yaml")}, nil)
+	assert.NotNil(t, err)
+
+	// Files which aren't components (other environments, generated files) are only loaded when named explicitly, and
+	// excluded directories aren't searched
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(baseDir, "rezolvr/env-prod.yaml"), []byte("name: prod\ndriver: kube\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(baseDir, "rezolvr/nested/deployment.yaml"),
+		[]byte("name: cache\n---\napiVersion: apps/v1\nkind: Deployment\n"), 0644))
+	writeTestFiles(t, baseDir, "rezolvr/out/generated.yaml")
+	files, err = ExpandComponentPaths([]string{filepath.Join(baseDir, "rezolvr"), filepath.Join(baseDir, "rezolvr/*-prod.yaml")},
+		[]string{stateFile, envFile, filepath.Join(baseDir, "rezolvr/out/")})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(baseDir, "rezolvr/app.yml"),
+		filepath.Join(baseDir, "rezolvr/db.yaml"),
+		filepath.Join(baseDir, "rezolvr/nested/cache.yaml"),
+	}, files)
+	files, err = ExpandComponentPaths([]string{filepath.Join(baseDir, "rezolvr/env-prod.yaml")}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(baseDir, "rezolvr/env-prod.yaml")}, files)
+}
+
+// Test_ExampleManifests - the components, environments and state of every manifest shipped with the examples can be
+// found
+func Test_ExampleManifests(t *testing.T) {
+	manifests, err := filepath.Glob(filepath.Join("..", "examples", "*", "*", ManifestFileName))
+	assert.Nil(t, err)
+	others, err := filepath.Glob(filepath.Join("..", "examples", "*", ManifestFileName))
+	assert.Nil(t, err)
+	manifests = append(manifests, others...)
+	assert.NotEmpty(t, manifests)
+	for _, curManifest := range manifests {
+		manifest, err := LoadManifest(curManifest, true)
+		if !assert.Nil(t, err, curManifest) {
+			continue
+		}
+		cla := CmdLineArgs{Command: "apply"}
+		manifest.ApplyTo(&cla)
+		files, err := ExpandComponentPaths(cla.ComponentsToAdd, append([]string{cla.StateFile}, cla.EnvFiles...))
+		assert.Nil(t, err, curManifest)
+		assert.NotEmpty(t, files, curManifest)
+		for _, curFile := range files {
+			content, err := LoadFile(curFile, true)
+			assert.Nil(t, err, curFile)
+			components, err := model.LoadComponents(content)
+			assert.Nil(t, err, curFile)
+			assert.NotEmpty(t, components, curFile)
+		}
+		for _, curEnv := range cla.EnvFiles {
+			_, err := os.Stat(curEnv)
+			assert.Nil(t, err, curManifest)
+		}
+	}
+}
+
+func Test_LoadManifest(t *testing.T) {
+	baseDir := t.TempDir()
+	manifestFile := filepath.Join(baseDir, ManifestFileName)
+
+	// A missing manifest is only an error when it was explicitly requested
+	manifest, err := LoadManifest(manifestFile, false)
+	assert.Nil(t, err)
+	assert.Nil(t, manifest)
+	_, err = LoadManifest(manifestFile, true)
+	assert.NotNil(t, err)
+
+	content := `components:
+  - ./rezolvr/
+environments:
+  - ./rezolvr/env.yaml
+state: ./rezolvr/state.yaml
+outputDir: ./deploy
+`
+	assert.Nil(t, ioutil.WriteFile(manifestFile, []byte(content), 0644))
+	manifest, err = LoadManifest(manifestFile, true)
+	assert.Nil(t, err)
+
+	// Command line values win; everything else comes from the manifest
+	cla := CmdLineArgs{Command: "apply", StateFile: "other.yaml"}
+	manifest.ApplyTo(&cla)
+	assert.Equal(t, []string{filepath.Join(baseDir, "rezolvr")}, cla.ComponentsToAdd)
+	assert.Equal(t, []string{filepath.Join(baseDir, "rezolvr/env.yaml")}, cla.EnvFiles)
+	assert.Equal(t, "other.yaml", cla.StateFile)
+	assert.Equal(t, "", cla.OutputDir)
+	assert.Equal(t, filepath.Join(baseDir, "deploy"), manifest.GetSettings().OutputDir)
+
+	// Unknown keys are rejected
+	assert.Nil(t, ioutil.WriteFile(manifestFile, []byte("state: ./state.yaml\nlogLevl: debug\n"), 0644))
+	_, err = LoadManifest(manifestFile, true)
+	assert.NotNil(t, err)
+}
+
+func Test_LoadFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	// A missing file which doesn't have to exist has no content
+	content, err := LoadFile(missing, false)
+	assert.Nil(t, err)
+	assert.Empty(t, content)
+
+	_, err = LoadFile(missing, true)
+	assert.True(t, os.IsNotExist(err))
+}
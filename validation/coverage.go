@@ -0,0 +1,57 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"rezolvr/model"
+	"sort"
+)
+
+// UnsupportedResource is a provided resource which the driver can't render, so it would produce no output
+type UnsupportedResource struct {
+	ComponentID string
+	ResourceID  string
+	Type        string
+}
+
+// String formats the unsupported resource for display
+func (ur UnsupportedResource) String() string {
+	return fmt.Sprintf("%s provides %s (type %s)", ur.ComponentID, ur.ResourceID, ur.Type)
+}
+
+// GetUnsupportedResources lists every provided resource whose type isn't one of the supported types, ordered by
+// component and resource. Resources which are excused by the platform settings aren't included.
+func GetUnsupportedResources(components map[string]*model.Component, supportedTypes []string, platformSettings map[string]*model.Platform) []UnsupportedResource {
+	supported := make(map[string]bool)
+	for _, curType := range supportedTypes {
+		supported[curType] = true
+	}
+	unsupported := make([]UnsupportedResource, 0)
+	for curComponentID, curComponent := range components {
+		for curResourceID, curProvides := range curComponent.Provides {
+			if !supported[curProvides.Type] && len(model.OutputExcusedBy(curProvides.Name, platformSettings)) == 0 {
+				unsupported = append(unsupported, UnsupportedResource{ComponentID: curComponentID, ResourceID: curResourceID, Type: curProvides.Type})
+			}
+		}
+	}
+	sort.Slice(unsupported, func(i, j int) bool {
+		if unsupported[i].ComponentID != unsupported[j].ComponentID {
+			return unsupported[i].ComponentID < unsupported[j].ComponentID
+		}
+		return unsupported[i].ResourceID < unsupported[j].ResourceID
+	})
+	return unsupported
+}
@@ -0,0 +1,99 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"rezolvr/model"
+	"sort"
+)
+
+// ParamLocation identifies a single param within a component's needs, uses or provides section
+type ParamLocation struct {
+	ComponentID string
+	Section     string
+	ResourceID  string
+	Param       string
+}
+
+// ParamDrift describes a param whose stored value differs from the freshly resolved one
+type ParamDrift struct {
+	ParamLocation
+	OldValue string
+	NewValue string
+	// Sensitive - the param belongs to a secret, or holds the value of one, so its values aren't displayed
+	Sensitive bool
+}
+
+// String formats the drift for display. The values of sensitive params are masked.
+func (pd ParamDrift) String() string {
+	oldValue, newValue := pd.DisplayValues()
+	return fmt.Sprintf("%s %s %s (%s): '%s' -> '%s'", pd.ComponentID, pd.Section, pd.ResourceID, pd.Param, oldValue, newValue)
+}
+
+// DisplayValues - the old and new values, masked when the param is sensitive (an empty value isn't masked)
+func (pd ParamDrift) DisplayValues() (string, string) {
+	if !pd.Sensitive {
+		return pd.OldValue, pd.NewValue
+	}
+	return model.MaskValue(pd.OldValue), model.MaskValue(pd.NewValue)
+}
+
+func snapshotResources(snapshot map[ParamLocation]string, componentID string, section string, resources map[string]*model.Resource) {
+	for resID, curResource := range resources {
+		for paramName, curParam := range curResource.Params {
+			snapshot[ParamLocation{ComponentID: componentID, Section: section, ResourceID: resID, Param: paramName}] = curParam.Value
+		}
+	}
+}
+
+// SnapshotParamValues copies every param value of the given components. The resolver updates params in place,
+// so this must be called before resolution to compare the stored values with the new ones.
+func SnapshotParamValues(components map[string]*model.Component) map[ParamLocation]string {
+	snapshot := make(map[ParamLocation]string)
+	for curComponentID, curComponent := range components {
+		snapshotResources(snapshot, curComponentID, "needs", curComponent.Needs)
+		snapshotResources(snapshot, curComponentID, "uses", curComponent.Uses)
+		snapshotResources(snapshot, curComponentID, "provides", curComponent.Provides)
+	}
+	return snapshot
+}
+
+// GetParamDrift compares two snapshots and lists every param which was added, removed or changed. Params of secrets,
+// params named like secrets, and params holding one of the secret values (which are often copied into other
+// resources), are sensitive.
+func GetParamDrift(before map[ParamLocation]string, after map[ParamLocation]string, secretValues map[string]bool) []ParamDrift {
+	drift := make([]ParamDrift, 0)
+	for loc, oldValue := range before {
+		newValue, ok := after[loc]
+		if !ok || newValue != oldValue {
+			drift = append(drift, ParamDrift{ParamLocation: loc, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	for loc, newValue := range after {
+		if _, ok := before[loc]; !ok {
+			drift = append(drift, ParamDrift{ParamLocation: loc, NewValue: newValue})
+		}
+	}
+	for i := range drift {
+		drift[i].Sensitive = model.IsSecretParam(drift[i].ResourceID, drift[i].Param) || secretValues[drift[i].OldValue] ||
+			secretValues[drift[i].NewValue]
+	}
+
+	sort.Slice(drift, func(i, j int) bool {
+		return drift[i].String() < drift[j].String()
+	})
+	return drift
+}
@@ -16,15 +16,46 @@ package validation
 
 import (
 	"errors"
-	"log"
+	"fmt"
 	"rezolvr/model"
 	"rezolvr/utils"
+	"sort"
 )
 
+// EnvPropertyAdded - an environment property exists in the environment file, but not in the state
+const EnvPropertyAdded = "added"
+
+// EnvPropertyRemoved - an environment property exists in the state, but not in the environment file
+const EnvPropertyRemoved = "removed"
+
+// EnvPropertyChanged - an environment property exists in both places, but the values differ
+const EnvPropertyChanged = "changed"
+
+// EnvironmentChange describes a single difference between the state's environment properties and an environment file
+type EnvironmentChange struct {
+	ResourceID string
+	Param      string
+	Change     string
+}
+
+// String formats the change for display, e.g. "- environment.properties:dbEnvProps (db_host)"
+func (ec EnvironmentChange) String() string {
+	prefix := "~"
+	if ec.Change == EnvPropertyAdded {
+		prefix = "+"
+	} else if ec.Change == EnvPropertyRemoved {
+		prefix = "-"
+	}
+	if len(ec.Param) > 0 {
+		return fmt.Sprintf("%s %s (%s)", prefix, ec.ResourceID, ec.Param)
+	}
+	return fmt.Sprintf("%s %s", prefix, ec.ResourceID)
+}
+
 // RemoveComponentsFromState - Remove components which have been marked for deletion
 func RemoveComponentsFromState(state *model.State, componentsToDelete []string) {
 	for _, res := range componentsToDelete {
-		log.Printf("About to delete: %v\n", res)
+		model.GetLogger().WithField(model.LogFieldComponent, res).Infof("Deleting component from the state")
 		delete(state.Components, res)
 	}
 }
@@ -43,7 +74,7 @@ func GetImpactedComponents(state *model.State, componentsNeedingUpdate map[strin
 						// This may have been previously found. Make sure it's new
 						_, okExists := componentsNeedingUpdate[curExistingComponentID]
 						if !okExists {
-							log.Printf("Component found which needs recalc: %s - %s\n", curExistingComponentID, curNeedID)
+							model.GetLogger().WithField(model.LogFieldComponent, curExistingComponentID).WithField(model.LogFieldResource, curNeedID).Debugf("Component found which needs recalc")
 							componentsNeedingUpdate[curExistingComponentID] = curExistingComponent
 							componentAdded = true
 						}
@@ -55,17 +86,17 @@ func GetImpactedComponents(state *model.State, componentsNeedingUpdate map[strin
 	}
 
 	if componentAdded {
-		log.Println("Dependencies found on existing components. Recursing.")
+		model.GetLogger().Tracef("Dependencies found on existing components. Recursing.")
 		GetImpactedComponents(state, componentsNeedingUpdate)
 	} else {
-		log.Println("No new dependencies found... returning.")
+		model.GetLogger().Tracef("No new dependencies found... returning.")
 	}
 	return componentsNeedingUpdate
 }
 
 // ValidateState ensures that all existing components have been resolved
 func ValidateState(state *model.State) error {
-	log.Println("Validating the integrity of the current state...")
+	model.GetLogger().Debugf("Validating the integrity of the current state...")
 
 	// Collect all of the 'provides' resources into a single map
 	allProvides := make(map[string]*model.Resource)
@@ -87,7 +118,114 @@ func ValidateState(state *model.State) error {
 			}
 		}
 	}
-	log.Println("State validation complete")
+	model.GetLogger().Debugf("State validation complete")
 
 	return nil
 }
+
+// DiffEnvironmentProperties compares the environment properties held in the state with those supplied by
+// the environment file(s). Whole categories which were added or removed are reported without a param name.
+func DiffEnvironmentProperties(stateProps map[string]*model.Resource, envProps map[string]*model.Resource) []EnvironmentChange {
+	changes := make([]EnvironmentChange, 0)
+	for resID, stateRes := range stateProps {
+		envRes, ok := envProps[resID]
+		if !ok {
+			changes = append(changes, EnvironmentChange{ResourceID: resID, Change: EnvPropertyRemoved})
+			continue
+		}
+		for paramName, stateParam := range stateRes.Params {
+			envParam, ok := envRes.Params[paramName]
+			if !ok {
+				changes = append(changes, EnvironmentChange{ResourceID: resID, Param: paramName, Change: EnvPropertyRemoved})
+			} else if envParam.Value != stateParam.Value {
+				changes = append(changes, EnvironmentChange{ResourceID: resID, Param: paramName, Change: EnvPropertyChanged})
+			}
+		}
+		for paramName := range envRes.Params {
+			if _, ok := stateRes.Params[paramName]; !ok {
+				changes = append(changes, EnvironmentChange{ResourceID: resID, Param: paramName, Change: EnvPropertyAdded})
+			}
+		}
+	}
+	for resID := range envProps {
+		if _, ok := stateProps[resID]; !ok {
+			changes = append(changes, EnvironmentChange{ResourceID: resID, Change: EnvPropertyAdded})
+		}
+	}
+
+	// Map iteration order is random; keep the output stable
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].ResourceID != changes[j].ResourceID {
+			return changes[i].ResourceID < changes[j].ResourceID
+		}
+		return changes[i].Param < changes[j].Param
+	})
+	return changes
+}
+
+// GetStaleEnvironmentProperties lists the environment categories held in the state which no longer exist in any environment file
+func GetStaleEnvironmentProperties(stateProps map[string]*model.Resource, envProps map[string]*model.Resource) []string {
+	stale := make([]string, 0)
+	for _, curChange := range DiffEnvironmentProperties(stateProps, envProps) {
+		if curChange.Change == EnvPropertyRemoved && len(curChange.Param) == 0 {
+			stale = append(stale, curChange.ResourceID)
+		}
+	}
+	return stale
+}
+
+// GetChangedEnvironmentCategories reduces a list of environment changes to the unique resource IDs of the affected categories.
+// When the environment is merged rather than synchronized, categories removed from the environment file stay in the state, so they're not considered changed.
+func GetChangedEnvironmentCategories(changes []EnvironmentChange, synchronized bool) []string {
+	found := make(map[string]bool)
+	categories := make([]string, 0)
+	for _, curChange := range changes {
+		if !synchronized && curChange.Change == EnvPropertyRemoved && len(curChange.Param) == 0 {
+			continue
+		}
+		if !found[curChange.ResourceID] {
+			found[curChange.ResourceID] = true
+			categories = append(categories, curChange.ResourceID)
+		}
+	}
+	return categories
+}
+
+// GetComponentsNeedingResources locates the existing components which need any of the given resources
+func GetComponentsNeedingResources(state *model.State, resourceIDs []string) map[string]*model.Component {
+	found := make(map[string]*model.Component)
+	for curComponentID, curComponent := range state.Components {
+		if curComponentID == "environment.properties" {
+			continue
+		}
+		for _, curResourceID := range resourceIDs {
+			if _, ok := curComponent.Needs[curResourceID]; ok {
+				model.GetLogger().WithField(model.LogFieldComponent, curComponentID).WithField(model.LogFieldResource, curResourceID).Debugf("Component found which needs recalc")
+				found[curComponentID] = curComponent
+				break
+			}
+		}
+	}
+	return found
+}
+
+// MergeEnvironmentProperties adds or overwrites the state's environment properties with those from the environment file(s).
+// Categories which only exist in the state are left untouched.
+func MergeEnvironmentProperties(state *model.State, envProps map[string]*model.Resource) {
+	stateProps := state.Components["environment.properties"].Provides
+	for envKey, envVal := range envProps {
+		stateProps[envKey] = envVal
+	}
+}
+
+// SyncEnvironmentProperties treats the environment file(s) as authoritative: the state's environment properties are
+// replaced, and every category / param which was dropped, added or changed is returned
+func SyncEnvironmentProperties(state *model.State, envProps map[string]*model.Resource) []EnvironmentChange {
+	envComponent := state.Components["environment.properties"]
+	changes := DiffEnvironmentProperties(envComponent.Provides, envProps)
+	envComponent.Provides = make(map[string]*model.Resource)
+	for envKey, envVal := range envProps {
+		envComponent.Provides[envKey] = envVal
+	}
+	return changes
+}
@@ -0,0 +1,176 @@
+// © Copyright IBM Corporation 2020. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"rezolvr/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getEnvResource(name string, params map[string]string) *model.Resource {
+	res := model.Resource{Name: name, Type: "environment.properties"}
+	res.Params = make(map[string]*model.Param)
+	for k, v := range params {
+		res.Params[k] = &model.Param{Name: k, Value: v}
+	}
+	return &res
+}
+
+func getStateWithEnvProps(props map[string]*model.Resource) *model.State {
+	state, _ := model.LoadState(nil)
+	state.Components["environment.properties"].Provides = props
+	return state
+}
+
+func Test_DiffEnvironmentProperties(t *testing.T) {
+	stateProps := map[string]*model.Resource{
+		"environment.properties:dbEnvProps":  getEnvResource("dbEnvProps", map[string]string{"db_host": "localhost", "db_port": "5432"}),
+		"environment.properties:oldEnvProps": getEnvResource("oldEnvProps", map[string]string{"old": "value"}),
+	}
+	envProps := map[string]*model.Resource{
+		"environment.properties:dbEnvProps":  getEnvResource("dbEnvProps", map[string]string{"db_host": "db.example.com", "db_name": "catalog"}),
+		"environment.properties:appEnvProps": getEnvResource("appEnvProps", map[string]string{"app_message": "Hello"}),
+	}
+
+	changes := DiffEnvironmentProperties(stateProps, envProps)
+	assert.Equal(t, 5, len(changes))
+	assert.Equal(t, EnvironmentChange{ResourceID: "environment.properties:appEnvProps", Change: EnvPropertyAdded}, changes[0])
+	assert.Equal(t, EnvironmentChange{ResourceID: "environment.properties:dbEnvProps", Param: "db_host", Change: EnvPropertyChanged}, changes[1])
+	assert.Equal(t, EnvironmentChange{ResourceID: "environment.properties:dbEnvProps", Param: "db_name", Change: EnvPropertyAdded}, changes[2])
+	assert.Equal(t, EnvironmentChange{ResourceID: "environment.properties:dbEnvProps", Param: "db_port", Change: EnvPropertyRemoved}, changes[3])
+	assert.Equal(t, EnvironmentChange{ResourceID: "environment.properties:oldEnvProps", Change: EnvPropertyRemoved}, changes[4])
+	assert.Equal(t, "- environment.properties:oldEnvProps", changes[4].String())
+
+	stale := GetStaleEnvironmentProperties(stateProps, envProps)
+	assert.Equal(t, []string{"environment.properties:oldEnvProps"}, stale)
+}
+
+func Test_SyncEnvironmentProperties(t *testing.T) {
+	state := getStateWithEnvProps(map[string]*model.Resource{
+		"environment.properties:oldEnvProps": getEnvResource("oldEnvProps", map[string]string{"old": "value"}),
+	})
+	envProps := map[string]*model.Resource{
+		"environment.properties:appEnvProps": getEnvResource("appEnvProps", map[string]string{"app_message": "Hello"}),
+	}
+
+	// Merging keeps stale properties around
+	MergeEnvironmentProperties(state, envProps)
+	assert.Equal(t, 2, len(state.Components["environment.properties"].Provides))
+
+	// Synchronizing drops them
+	changes := SyncEnvironmentProperties(state, envProps)
+	assert.Equal(t, 1, len(changes))
+	assert.Equal(t, EnvPropertyRemoved, changes[0].Change)
+	_, ok := state.Components["environment.properties"].Provides["environment.properties:oldEnvProps"]
+	assert.False(t, ok)
+	_, ok = state.Components["environment.properties"].Provides["environment.properties:appEnvProps"]
+	assert.True(t, ok)
+}
+
+func Test_GetComponentsNeedingResources(t *testing.T) {
+	stateProps := map[string]*model.Resource{
+		"environment.properties:dbEnvProps":  getEnvResource("dbEnvProps", map[string]string{"db_password": "old"}),
+		"environment.properties:oldEnvProps": getEnvResource("oldEnvProps", map[string]string{"old": "value"}),
+	}
+	envProps := map[string]*model.Resource{
+		"environment.properties:dbEnvProps": getEnvResource("dbEnvProps", map[string]string{"db_password": "new"}),
+	}
+	changes := DiffEnvironmentProperties(stateProps, envProps)
+
+	// A merge keeps the removed category, so only the changed one is reported
+	assert.Equal(t, []string{"environment.properties:dbEnvProps"}, GetChangedEnvironmentCategories(changes, false))
+	assert.Equal(t, 2, len(GetChangedEnvironmentCategories(changes, true)))
+
+	state := getStateWithEnvProps(stateProps)
+	db := model.Component{Name: "postgres", Type: "resource.db.postgres"}
+	db.Needs = map[string]*model.Resource{"environment.properties:dbEnvProps": getEnvResource("dbEnvProps", nil)}
+	state.Components["resource.db.postgres:postgres"] = &db
+	app := model.Component{Name: "catalog", Type: "resource.web.app"}
+	app.Needs = map[string]*model.Resource{"service.db.postgres:mydb": {Name: "mydb", Type: "service.db.postgres"}}
+	state.Components["resource.web.app:catalog"] = &app
+
+	found := GetComponentsNeedingResources(state, GetChangedEnvironmentCategories(changes, false))
+	assert.Equal(t, 1, len(found))
+	_, ok := found["resource.db.postgres:postgres"]
+	assert.True(t, ok)
+}
+
+func Test_GetParamDrift(t *testing.T) {
+	db := model.Component{Name: "postgres", Type: "resource.db.postgres"}
+	db.Provides = map[string]*model.Resource{"service.db.postgres:mydb": getEnvResource("mydb", map[string]string{"db_host": "localhost", "db_port": "5432"})}
+	components := map[string]*model.Component{"resource.db.postgres:postgres": &db}
+
+	before := SnapshotParamValues(components)
+	assert.Equal(t, 0, len(GetParamDrift(before, SnapshotParamValues(components), nil)))
+
+	db.Provides["service.db.postgres:mydb"].Params["db_host"].Value = "db.example.com"
+	drift := GetParamDrift(before, SnapshotParamValues(components), nil)
+	assert.Equal(t, 1, len(drift))
+	assert.Equal(t, "provides", drift[0].Section)
+	assert.Equal(t, "db_host", drift[0].Param)
+	assert.Equal(t, "localhost", drift[0].OldValue)
+	assert.Equal(t, "db.example.com", drift[0].NewValue)
+	assert.False(t, drift[0].Sensitive)
+
+	// The values of secrets are masked, along with the params which copied them
+	db.Uses = map[string]*model.Resource{"environment.secret:dbcreds": getEnvResource("dbcreds", map[string]string{"db_password": "passwordie"})}
+	db.Provides["service.db.postgres:mydb"].Params["db_password"] = &model.Param{Name: "db_password", Value: "passwordie"}
+	drift = GetParamDrift(before, SnapshotParamValues(components), model.SecretValues(components))
+	assert.Equal(t, 3, len(drift))
+	for _, curDrift := range drift {
+		assert.NotContains(t, curDrift.String(), "passwordie")
+	}
+	assert.False(t, drift[0].Sensitive)
+	assert.Equal(t, "resource.db.postgres:postgres provides service.db.postgres:mydb (db_password): '' -> '******'", drift[1].String())
+	assert.True(t, drift[2].Sensitive)
+
+	// So are params named like secrets, and the params which copied them, whatever the resource
+	db.Uses = map[string]*model.Resource{"environment": getEnvResource("", map[string]string{"DB_PW": "hunter2", "DB_USER": "admin"})}
+	db.Needs = map[string]*model.Resource{"service.cache:cache": getEnvResource("cache", map[string]string{"auth": "hunter2"})}
+	drift = GetParamDrift(map[ParamLocation]string{}, SnapshotParamValues(components), model.SecretValues(components))
+	for _, curDrift := range drift {
+		assert.Equal(t, curDrift.Param == "DB_PW" || curDrift.Param == "db_password" || curDrift.Param == "auth", curDrift.Sensitive, curDrift.String())
+		assert.NotContains(t, curDrift.String(), "hunter2")
+	}
+}
+
+func Test_GetUnsupportedResources(t *testing.T) {
+	app := model.Component{Name: "welcome", Type: "resource.web.app", Provides: map[string]*model.Resource{
+		"service.web.app:welcome": {Name: "welcome", Type: "service.web.app"},
+	}}
+	cache := model.Component{Name: "cache", Type: "resource.cache.redis", Provides: map[string]*model.Resource{
+		"service.cache.redis:cache": {Name: "cache", Type: "service.cache.redis"},
+	}}
+	managedCache := model.Component{Name: "managed", Type: "resource.cache.redis", Provides: map[string]*model.Resource{
+		"service.cache.redis:managed": {Name: "managed", Type: "service.cache.redis"},
+	}}
+	components := map[string]*model.Component{"resource.web.app:welcome": &app, "resource.cache.redis:cache": &cache,
+		"resource.cache.redis:managed": &managedCache}
+	supportedTypes := []string{"service.web.app"}
+
+	unsupported := GetUnsupportedResources(components, supportedTypes, nil)
+	assert.Equal(t, 2, len(unsupported))
+	assert.Equal(t, "resource.cache.redis:cache provides service.cache.redis:cache (type service.cache.redis)", unsupported[0].String())
+	assert.Equal(t, "resource.cache.redis:managed", unsupported[1].ComponentID)
+
+	// External resources, and resources which have been opted out, don't need any output
+	platformSettings := map[string]*model.Platform{
+		"managed": {Params: map[string]*model.Param{model.PlatformParamIsExternal: {Name: model.PlatformParamIsExternal, Value: "true"}}},
+		"cache":   {Params: map[string]*model.Param{model.PlatformParamSkipOutput: {Name: model.PlatformParamSkipOutput, Value: "true"}}},
+	}
+	assert.Equal(t, 0, len(GetUnsupportedResources(components, supportedTypes, platformSettings)))
+}